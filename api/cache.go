@@ -0,0 +1,203 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+// Wrap a provider storing the fetched candles on disk
+// There is a cache file for every symbol and resolution inside Dir
+// It remembers which time ranges were already requested so only the missing ranges (gaps) are fetched from the Source
+// The period that is still open is never considered cached, so its candle is refreshed on the next request
+// It is safe for concurrent use: the requests of the same symbol and resolution share a cache file, so they run one at a time
+type CachedProvider struct {
+	Source CandleProvider
+	Dir    string
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex //By cache file
+}
+
+// The content of a cache file
+// Ranges are the sorted and non overlapping [from, to] intervals already fetched
+type cacheEntry struct {
+	Ranges  [][2]int64   `json:"ranges"`
+	Candles []fileCandle `json:"candles"`
+}
+
+// Retrieve the candles from the cache fetching from the source only the gaps
+func (provider *CachedProvider) GetCandles(symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	period, err := ResolutionSeconds(resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	path := provider.path(symbol, resolution)
+	lock := provider.lock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := loadCacheEntry(path)
+	if err != nil {
+		return nil, err
+	}
+
+	candles := entry.candles()
+	fetched := false
+	for _, gap := range missingRanges(entry.Ranges, from, to) {
+		res, err := provider.Source.GetCandles(symbol, resolution, gap[0], gap[1])
+		if err != nil {
+			return nil, err
+		}
+		candles = data.Merge(candles, res)
+		fetched = true
+
+		//Only the closed periods are complete
+		if end := data.PeriodStart(time.Now().Unix(), period) - 1; gap[1] > end {
+			gap[1] = end
+		}
+		if gap[0] <= gap[1] {
+			entry.Ranges = addRange(entry.Ranges, gap)
+		}
+	}
+
+	if fetched {
+		entry.setCandles(candles)
+		if err := entry.save(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var res []data.Candle
+	for _, candle := range candles {
+		if candle.Timestamp >= from && candle.Timestamp <= to {
+			res = append(res, candle)
+		}
+	}
+	return res, nil
+}
+
+// Retrieve the lock of a cache file
+func (provider *CachedProvider) lock(path string) *sync.Mutex {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.locks == nil {
+		provider.locks = make(map[string]*sync.Mutex)
+	}
+	if provider.locks[path] == nil {
+		provider.locks[path] = &sync.Mutex{}
+	}
+	return provider.locks[path]
+}
+
+// Build the cache file path for the symbol and resolution
+func (provider *CachedProvider) path(symbol, resolution string) string {
+	return filepath.Join(provider.Dir, FileName(symbol)+"_"+resolution+".json")
+}
+
+// Read a cache file, a missing file is an empty cache
+func loadCacheEntry(path string) (cacheEntry, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cacheEntry{}, nil
+	}
+	if err != nil {
+		return cacheEntry{}, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return cacheEntry{}, fmt.Errorf("reading cache %v: %w", path, err)
+	}
+	return entry, nil
+}
+
+// Write the cache file creating the directory if needed
+func (entry *cacheEntry) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
+
+func (entry *cacheEntry) candles() []data.Candle {
+	candles := make([]data.Candle, 0, len(entry.Candles))
+	for _, c := range entry.Candles {
+		candles = append(candles, c.toCandle())
+	}
+	return candles
+}
+
+func (entry *cacheEntry) setCandles(candles []data.Candle) {
+	entry.Candles = make([]fileCandle, 0, len(candles))
+	for _, c := range candles {
+		entry.Candles = append(entry.Candles, toFileCandle(c))
+	}
+}
+
+// Find the parts of [from, to] not covered by the sorted and non overlapping ranges
+func missingRanges(ranges [][2]int64, from, to int64) [][2]int64 {
+	var gaps [][2]int64
+
+	for _, r := range ranges {
+		if r[1] < from {
+			continue
+		}
+		if r[0] > to {
+			break
+		}
+		if r[0] > from {
+			gaps = append(gaps, [2]int64{from, r[0] - 1})
+		}
+		from = r[1] + 1
+	}
+
+	if from <= to {
+		gaps = append(gaps, [2]int64{from, to})
+	}
+	return gaps
+}
+
+// Insert a range keeping the ranges sorted and merging the overlapping or adjacent ones
+func addRange(ranges [][2]int64, newRange [2]int64) [][2]int64 {
+	var res [][2]int64
+	inserted := false
+
+	for _, r := range ranges {
+		switch {
+		case r[1]+1 < newRange[0]:
+			res = append(res, r)
+		case newRange[1]+1 < r[0]:
+			if !inserted {
+				res = append(res, newRange)
+				inserted = true
+			}
+			res = append(res, r)
+		default:
+			if r[0] < newRange[0] {
+				newRange[0] = r[0]
+			}
+			if r[1] > newRange[1] {
+				newRange[1] = r[1]
+			}
+		}
+	}
+
+	if !inserted {
+		res = append(res, newRange)
+	}
+	return res
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+func TestMissingRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranges   [][2]int64
+		from, to int64
+		expected [][2]int64
+	}{
+		{"empty cache", nil, 10, 20, [][2]int64{{10, 20}}},
+		{"covered", [][2]int64{{0, 30}}, 10, 20, nil},
+		{"before", [][2]int64{{15, 30}}, 10, 20, [][2]int64{{10, 14}}},
+		{"after", [][2]int64{{0, 14}}, 10, 20, [][2]int64{{15, 20}}},
+		{"around", [][2]int64{{12, 14}}, 10, 20, [][2]int64{{10, 11}, {15, 20}}},
+		{"holes", [][2]int64{{0, 11}, {13, 14}, {18, 30}}, 10, 20, [][2]int64{{12, 12}, {15, 17}}},
+		{"outside", [][2]int64{{0, 5}, {25, 30}}, 10, 20, [][2]int64{{10, 20}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := missingRanges(test.ranges, test.from, test.to); !reflect.DeepEqual(got, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestAddRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranges   [][2]int64
+		newRange [2]int64
+		expected [][2]int64
+	}{
+		{"empty", nil, [2]int64{10, 20}, [][2]int64{{10, 20}}},
+		{"before", [][2]int64{{30, 40}}, [2]int64{10, 20}, [][2]int64{{10, 20}, {30, 40}}},
+		{"after", [][2]int64{{0, 5}}, [2]int64{10, 20}, [][2]int64{{0, 5}, {10, 20}}},
+		{"adjacent", [][2]int64{{0, 9}, {21, 30}}, [2]int64{10, 20}, [][2]int64{{0, 30}}},
+		{"overlapping", [][2]int64{{5, 12}, {18, 25}}, [2]int64{10, 20}, [][2]int64{{5, 25}}},
+		{"inside", [][2]int64{{0, 30}}, [2]int64{10, 20}, [][2]int64{{0, 30}}},
+		{"between", [][2]int64{{0, 5}, {40, 50}}, [2]int64{10, 20}, [][2]int64{{0, 5}, {10, 20}, {40, 50}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := addRange(test.ranges, test.newRange); !reflect.DeepEqual(got, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+// A source of hourly candles counting the requests
+type countingSource struct {
+	mu       sync.Mutex
+	requests [][2]int64
+}
+
+func (source *countingSource) GetCandles(symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	source.mu.Lock()
+	source.requests = append(source.requests, [2]int64{from, to})
+	source.mu.Unlock()
+
+	var candles []data.Candle
+	for t := from - from%3600; t <= to; t += 3600 {
+		if t >= from {
+			candles = append(candles, data.Candle{Timestamp: t, Open: 1, High: 2, Low: 0, Close: 1})
+		}
+	}
+	return candles, nil
+}
+
+func TestCachedProviderFetchesOnlyTheGaps(t *testing.T) {
+	source := &countingSource{}
+	provider := &CachedProvider{Source: source, Dir: t.TempDir()}
+
+	const hour = 3600
+	if _, err := provider.GetCandles("BINANCE:BTCUSDT", "60", 10*hour, 20*hour-1); err != nil {
+		t.Fatal(err)
+	}
+	candles, err := provider.GetCandles("BINANCE:BTCUSDT", "60", 5*hour, 25*hour-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(candles) != 20 {
+		t.Fatalf("expected 20 candles, got %v", len(candles))
+	}
+	expected := [][2]int64{{10 * hour, 20*hour - 1}, {5 * hour, 10*hour - 1}, {20 * hour, 25*hour - 1}}
+	if !reflect.DeepEqual(source.requests, expected) {
+		t.Fatalf("expected the requests %v, got %v", expected, source.requests)
+	}
+}
+
+func TestCachedProviderIsSafeForConcurrentUse(t *testing.T) {
+	source := &countingSource{}
+	provider := &CachedProvider{Source: source, Dir: t.TempDir()}
+
+	const hour = 3600
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from := int64(i%4) * 10 * hour
+			if _, err := provider.GetCandles("BINANCE:BTCUSDT", "60", from, from+10*hour-1); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	//Every range is fetched once and the cache file has all of them
+	if len(source.requests) != 4 {
+		t.Fatalf("expected 4 requests, got %v", source.requests)
+	}
+	entry, err := loadCacheEntry(provider.path("BINANCE:BTCUSDT", "60"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Candles) != 40 || !reflect.DeepEqual(entry.Ranges, [][2]int64{{0, 40*hour - 1}}) {
+		t.Fatalf("expected 40 candles in the range [0, %v], got %v in %v", 40*hour-1, len(entry.Candles), entry.Ranges)
+	}
+}
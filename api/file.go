@@ -0,0 +1,258 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+// The placeholder replaced by the symbol in the Path of a FileProvider
+const SymbolPlaceholder = "{symbol}"
+
+// Retrieve the candles from local CSV or JSON files, used for offline backtests
+// If the Path contains {symbol} every symbol has its own file, e.g. testdata/{symbol}_30.json reads
+// testdata/BINANCE_BTCUSDT_30.json for BINANCE:BTCUSDT (see FileName)
+// Otherwise the file contains the history of a single symbol: the first requested symbol is the one of the file
+// and the requests for the other symbols fail
+// The candles are resampled to the requested resolution, so a single intraday file can serve the daily history too
+// It is safe for concurrent use
+//
+// CSV files have the columns timestamp,open,high,low,close,volume with an optional header
+// JSON files contain either an array of {"timestamp","open","high","low","close","volume"} objects
+// or a finnhub candle response ({"t","o","h","l","c","v"} arrays)
+type FileProvider struct {
+	Path string
+
+	mu      sync.Mutex
+	symbol  string
+	candles map[string][]data.Candle //By file
+}
+
+// The JSON representation of a candle in a file
+type fileCandle struct {
+	Timestamp int64   `json:"timestamp"`
+	Open      float32 `json:"open"`
+	High      float32 `json:"high"`
+	Low       float32 `json:"low"`
+	Close     float32 `json:"close"`
+	Volume    float32 `json:"volume"`
+}
+
+func (c fileCandle) toCandle() data.Candle {
+	return data.Candle{Open: c.Open, Close: c.Close, High: c.High, Low: c.Low, Volume: c.Volume, Timestamp: c.Timestamp}
+}
+
+func toFileCandle(c data.Candle) fileCandle {
+	return fileCandle{Timestamp: c.Timestamp, Open: c.Open, High: c.High, Low: c.Low, Close: c.Close, Volume: c.Volume}
+}
+
+// The JSON representation of a finnhub candle response
+type finnhubCandles struct {
+	O, H, L, C, V []float32
+	T             []int64
+	S             string
+}
+
+// Load the file of the symbol (only the first time) and retrieve the candles between from and to resampled to the given resolution
+func (provider *FileProvider) GetCandles(symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	period, err := ResolutionSeconds(resolution)
+	if err != nil {
+		return nil, err
+	}
+	candles, err := provider.load(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []data.Candle
+	for _, candle := range candles {
+		if candle.Timestamp >= from && candle.Timestamp <= to {
+			res = append(res, candle)
+		}
+	}
+
+	return data.Resample(res, period), nil
+}
+
+// Retrieve the candles of the file of the symbol, loading it the first time
+func (provider *FileProvider) load(symbol string) ([]data.Candle, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	path := provider.Path
+	if strings.Contains(path, SymbolPlaceholder) {
+		path = strings.ReplaceAll(path, SymbolPlaceholder, FileName(symbol))
+	} else if provider.symbol == "" {
+		provider.symbol = symbol
+	} else if symbol != provider.symbol {
+		return nil, fmt.Errorf("%v CONTAINS THE CANDLES OF %v, NOT OF %v: USE %v IN THE PATH TO READ A FILE FOR EVERY SYMBOL", provider.Path, provider.symbol, symbol, SymbolPlaceholder)
+	}
+
+	if candles, ok := provider.candles[path]; ok {
+		return candles, nil
+	}
+	candles, err := LoadCandles(path)
+	if err != nil {
+		return nil, err
+	}
+	if provider.candles == nil {
+		provider.candles = make(map[string][]data.Candle)
+	}
+	provider.candles[path] = candles
+	return candles, nil
+}
+
+// Read the candles from a CSV or JSON file, choosing the format from the extension
+func LoadCandles(path string) ([]data.Candle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var candles []data.Candle
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		candles, err = readCSV(file)
+	case ".json":
+		candles, err = readJSON(file)
+	default:
+		return nil, fmt.Errorf("UNSUPPORTED FILE FORMAT %q, USE .csv OR .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %v: %w", path, err)
+	}
+
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Timestamp < candles[j].Timestamp })
+	return candles, nil
+}
+
+// Parse the rows timestamp,open,high,low,close,volume skipping the header if present
+func readCSV(r io.Reader) ([]data.Candle, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 6
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var candles []data.Candle
+	for i, record := range records {
+		t, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil {
+			if i == 0 {
+				continue //Header
+			}
+			return nil, fmt.Errorf("line %v: invalid timestamp %q", i+1, record[0])
+		}
+
+		var values [5]float32
+		for j := range values {
+			value, err := strconv.ParseFloat(record[j+1], 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid value %q", i+1, record[j+1])
+			}
+			values[j] = float32(value)
+		}
+
+		candles = append(candles, data.Candle{
+			Timestamp: t,
+			Open:      values[0],
+			High:      values[1],
+			Low:       values[2],
+			Close:     values[3],
+			Volume:    values[4],
+		})
+	}
+
+	return candles, nil
+}
+
+// Parse either an array of candles or a finnhub candle response
+func readJSON(r io.Reader) ([]data.Candle, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "{") {
+		var res finnhubCandles
+		if err := json.Unmarshal(raw, &res); err != nil {
+			return nil, err
+		}
+		return toCandles(res.O, res.C, res.H, res.L, res.V, res.T)
+	}
+
+	var fileCandles []fileCandle
+	if err := json.Unmarshal(raw, &fileCandles); err != nil {
+		return nil, err
+	}
+
+	candles := make([]data.Candle, 0, len(fileCandles))
+	for _, c := range fileCandles {
+		candles = append(candles, c.toCandle())
+	}
+	return candles, nil
+}
+
+// Write the candles to a CSV (with a header) or JSON file, choosing the format from the extension
+// The file can be read back with LoadCandles
+func SaveCandles(path string, candles []data.Candle) error {
+	var write func(io.Writer, []data.Candle) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		write = writeCSV
+	case ".json":
+		write = writeJSON
+	default:
+		return fmt.Errorf("UNSUPPORTED FILE FORMAT %q, USE .csv OR .json", filepath.Ext(path))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, candles); err != nil {
+		file.Close()
+		return fmt.Errorf("writing %v: %w", path, err)
+	}
+	return file.Close()
+}
+
+// Write the rows timestamp,open,high,low,close,volume after the header
+func writeCSV(w io.Writer, candles []data.Candle) error {
+	float := func(value float32) string { return strconv.FormatFloat(float64(value), 'f', -1, 32) }
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"timestamp", "open", "high", "low", "close", "volume"}); err != nil {
+		return err
+	}
+	for _, c := range candles {
+		record := []string{strconv.FormatInt(c.Timestamp, 10), float(c.Open), float(c.High), float(c.Low), float(c.Close), float(c.Volume)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Write an array of candles
+func writeJSON(w io.Writer, candles []data.Candle) error {
+	fileCandles := make([]fileCandle, 0, len(candles))
+	for _, c := range candles {
+		fileCandles = append(fileCandles, toFileCandle(c))
+	}
+	return json.NewEncoder(w).Encode(fileCandles)
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	finnhub "github.com/Finnhub-Stock-API/finnhub-go/v2"
+	"github.com/frappaf/tradingBot/data"
+)
+
+const (
+	defaultMaxCandles        = 500 //Candles requested with a single call
+	defaultRequestsPerMinute = 60  //Free plan limit
+	defaultMaxRetries        = 5
+	initialBackoff           = time.Second
+)
+
+// Retrieve the candles from the finnhub API
+// Symbols with an exchange prefix (e.g. BINANCE:ETHUSDT) use the crypto candles, the others (e.g. AAPL) the stock candles
+// The Token is sent in the X-Finnhub-Token header
+// The BaseURL replaces the finnhub one (https://finnhub.io/api/v1) when set, e.g. to use a local stub server
+// Long ranges are split into chunks of MaxCandles candles, requested at most RequestsPerMinute times per minute
+// A chunk failing with 429 or 5xx is retried up to MaxRetries times with an exponential backoff
+// The zero values of MaxCandles, RequestsPerMinute and MaxRetries use the defaults
+type FinnhubProvider struct {
+	Token             string
+	BaseURL           string
+	MaxCandles        int
+	RequestsPerMinute int
+	MaxRetries        int
+
+	once    sync.Once
+	limiter *rateLimiter
+}
+
+// Initialize a new finnhub provider reading the token from the TOKEN env variable
+func NewFinnhubProvider() *FinnhubProvider {
+	return &FinnhubProvider{Token: os.Getenv("TOKEN")}
+}
+
+// Retrieve the candles for the given symbol, resolution, from and to
+// The range is requested chunk by chunk and the results are stitched into one ordered series without duplicates
+func (provider *FinnhubProvider) GetCandles(symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	period, err := ResolutionSeconds(resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	maxCandles := provider.MaxCandles
+	if maxCandles <= 0 {
+		maxCandles = defaultMaxCandles
+	}
+	chunk := period * int64(maxCandles)
+
+	cfg := finnhub.NewConfiguration()
+	cfg.AddDefaultHeader("X-Finnhub-Token", provider.Token)
+	if provider.BaseURL != "" {
+		cfg.Servers = finnhub.ServerConfigurations{{URL: provider.BaseURL}}
+	}
+	finnhubClient := finnhub.NewAPIClient(cfg).DefaultApi
+
+	var candles []data.Candle
+	for start := from; start <= to; start += chunk {
+		end := start + chunk - 1
+		if end > to {
+			end = to
+		}
+
+		res, err := provider.fetchWithRetry(finnhubClient, symbol, resolution, start, end)
+		if err != nil {
+			return nil, err
+		}
+		candles = data.Merge(candles, res)
+	}
+
+	return candles, nil
+}
+
+// Request a single chunk retrying on rate limit and server errors
+func (provider *FinnhubProvider) fetchWithRetry(client *finnhub.DefaultApiService, symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	provider.once.Do(func() {
+		perMinute := provider.RequestsPerMinute
+		if perMinute <= 0 {
+			perMinute = defaultRequestsPerMinute
+		}
+		provider.limiter = newRateLimiter(perMinute)
+	})
+
+	maxRetries := provider.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		provider.limiter.Wait()
+
+		candles, httpRes, err := request(client, symbol, resolution, from, to)
+		if err == nil {
+			return candles, nil
+		}
+
+		if !retryable(httpRes) || attempt >= maxRetries {
+			return nil, fmt.Errorf("fetching %v %v candles from %v to %v: %w", symbol, resolution, from, to, err)
+		}
+
+		wait := backoff
+		if httpRes != nil {
+			if seconds, err := strconv.Atoi(httpRes.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+		}
+		time.Sleep(wait)
+		backoff *= 2
+	}
+}
+
+// Request the candles to the crypto or the stock endpoint depending on the symbol
+func request(client *finnhub.DefaultApiService, symbol, resolution string, from, to int64) ([]data.Candle, *http.Response, error) {
+	if ParseSymbol(symbol).IsCrypto() {
+		res, httpRes, err := client.CryptoCandles(context.Background()).Symbol(symbol).Resolution(resolution).From(from).To(to).Execute()
+		if err != nil {
+			return nil, httpRes, err
+		}
+		candles, err := toCandles(res.GetO(), res.GetC(), res.GetH(), res.GetL(), res.GetV(), res.GetT())
+		return candles, httpRes, err
+	}
+
+	res, httpRes, err := client.StockCandles(context.Background()).Symbol(symbol).Resolution(resolution).From(from).To(to).Execute()
+	if err != nil {
+		return nil, httpRes, err
+	}
+	candles, err := toCandles(res.GetO(), res.GetC(), res.GetH(), res.GetL(), res.GetV(), res.GetT())
+	return candles, httpRes, err
+}
+
+// A request is retried when there is no response (network error), when it is rate limited or when the server fails
+func retryable(res *http.Response) bool {
+	return res == nil || res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+}
+
+// Build the candles from the columns of a finnhub response
+// The columns of a malformed or truncated response don't have the same length, that is an error
+func toCandles(o, c, h, l, v []float32, t []int64) ([]data.Candle, error) {
+	if len(o) != len(t) || len(c) != len(t) || len(h) != len(t) || len(l) != len(t) || len(v) != len(t) {
+		return nil, fmt.Errorf("the t, o, h, l, c, v arrays must have the same length")
+	}
+
+	candles := make([]data.Candle, 0, len(t))
+	for i := 0; i < len(t); i++ {
+		candles = append(candles, data.Candle{Open: o[i], Close: c[i], High: h[i], Low: l[i], Volume: v[i], Timestamp: t[i]})
+	}
+
+	return candles, nil
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Serve the same body to every request
+func staticServer(t *testing.T, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFinnhubProviderRejectsMalformedResponses(t *testing.T) {
+	tests := []struct {
+		name, body string
+		candles    int
+		fails      bool
+	}{
+		{"complete", `{"s":"ok","t":[0,60],"o":[1,2],"h":[2,3],"l":[0,1],"c":[2,3],"v":[5,6]}`, 2, false},
+		{"no data", `{"s":"no_data"}`, 0, false},
+		{"truncated close", `{"s":"ok","t":[0,60],"o":[1,2],"h":[2,3],"l":[0,1],"c":[2],"v":[5,6]}`, 0, true},
+		{"missing timestamps", `{"s":"ok","o":[1],"h":[2],"l":[0],"c":[2],"v":[5]}`, 0, true},
+		{"partial no data", `{"s":"no_data","c":[1]}`, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := staticServer(t, test.body)
+			provider := &FinnhubProvider{BaseURL: server.URL, RequestsPerMinute: 60000}
+
+			candles, err := provider.GetCandles("BINANCE:BTCUSDT", "1", 0, 60)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected an error, got %v candles", len(candles))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(candles) != test.candles {
+				t.Fatalf("expected %v candles, got %v", test.candles, len(candles))
+			}
+		})
+	}
+}
+
+// Serve a candle per minute from one minute before the requested from to the requested to,
+// so consecutive chunks overlap by a candle
+// The first failures requests are answered with the status
+type chunkServer struct {
+	failures int
+	status   int
+	mu       sync.Mutex
+	requests [][2]int64
+}
+
+func (server *chunkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+
+	server.mu.Lock()
+	server.requests = append(server.requests, [2]int64{from, to})
+	fail := len(server.requests) <= server.failures
+	server.mu.Unlock()
+
+	if fail {
+		w.WriteHeader(server.status)
+		return
+	}
+
+	var t, o []string
+	for timestamp := from - from%60 - 60; timestamp <= to; timestamp += 60 {
+		if timestamp < 0 {
+			continue
+		}
+		t = append(t, strconv.FormatInt(timestamp, 10))
+		o = append(o, "1")
+	}
+	column := "[" + strings.Join(o, ",") + "]"
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"s":"ok","t":[%v],"o":%v,"h":%v,"l":%v,"c":%v,"v":%v}`, strings.Join(t, ","), column, column, column, column, column)
+}
+
+func TestFinnhubProviderPagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxCandles int
+		from, to   int64
+		requests   [][2]int64
+	}{
+		{"single chunk", 10, 0, 299, [][2]int64{{0, 299}}},
+		{"exact chunks", 5, 0, 599, [][2]int64{{0, 299}, {300, 599}}},
+		{"last partial chunk", 5, 0, 659, [][2]int64{{0, 299}, {300, 599}, {600, 659}}},
+		{"unaligned from", 5, 30, 400, [][2]int64{{30, 329}, {330, 400}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &chunkServer{}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			provider := &FinnhubProvider{BaseURL: server.URL, MaxCandles: test.maxCandles, RequestsPerMinute: 60000}
+
+			candles, err := provider.GetCandles("BINANCE:BTCUSDT", "1", test.from, test.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(handler.requests, test.requests) {
+				t.Fatalf("expected the requests %v, got %v", test.requests, handler.requests)
+			}
+
+			//The overlapping candles are stitched without duplicates
+			first := test.from - test.from%60
+			if first > 0 {
+				first -= 60
+			}
+			expected := int((test.to-first)/60) + 1
+			if len(candles) != expected {
+				t.Fatalf("expected %v candles, got %v", expected, len(candles))
+			}
+			for i := 1; i < len(candles); i++ {
+				if candles[i].Timestamp != candles[i-1].Timestamp+60 {
+					t.Fatalf("candle %v at %v follows %v", i, candles[i].Timestamp, candles[i-1].Timestamp)
+				}
+			}
+		})
+	}
+}
+
+func TestFinnhubProviderRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		status     int
+		maxRetries int
+		requests   int
+		fails      bool
+	}{
+		{"rate limited", 1, http.StatusTooManyRequests, 2, 2, false},
+		{"server error", 1, http.StatusBadGateway, 2, 2, false},
+		{"too many failures", 2, http.StatusServiceUnavailable, 1, 2, true},
+		{"client error", 1, http.StatusUnauthorized, 2, 1, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := &chunkServer{failures: test.failures, status: test.status}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			provider := &FinnhubProvider{BaseURL: server.URL, MaxRetries: test.maxRetries, RequestsPerMinute: 60000}
+
+			candles, err := provider.GetCandles("BINANCE:BTCUSDT", "1", 0, 120)
+			if len(handler.requests) != test.requests {
+				t.Fatalf("expected %v requests, got %v", test.requests, len(handler.requests))
+			}
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected an error, got %v candles", len(candles))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(candles) != 3 {
+				t.Fatalf("expected 3 candles, got %v", len(candles))
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// Spread the requests so that at most perMinute requests are done every minute
+// It is safe for concurrent use
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// Block until the next request can be done
+func (limiter *rateLimiter) Wait() {
+	limiter.mu.Lock()
+	now := time.Now()
+	wait := limiter.next.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	limiter.next = now.Add(wait + limiter.interval)
+	limiter.mu.Unlock()
+
+	time.Sleep(wait)
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	day  int64 = 24 * 60 * 60
+	week       = 7 * day
+)
+
+// Retrieve the length in seconds of a finnhub resolution
+// The supported resolutions are 1, 5, 15, 30, 60 (minutes), D (day) and W (week)
+func ResolutionSeconds(resolution string) (int64, error) {
+	switch resolution {
+	case "D":
+		return day, nil
+	case "W":
+		return week, nil
+	}
+
+	minutes, err := strconv.Atoi(resolution)
+	if err != nil || minutes <= 0 {
+		return 0, fmt.Errorf("UNSUPPORTED RESOLUTION %q", resolution)
+	}
+	return int64(minutes) * 60, nil
+}
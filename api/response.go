@@ -1,21 +1,19 @@
 package api
 
 import (
-	"context"
-	"os"
-
-	finnhub "github.com/Finnhub-Stock-API/finnhub-go/v2"
+	"github.com/frappaf/tradingBot/data"
 )
 
-// Initialize a new finnhub configuration and retrieve a finnhub.Cryptocandles for the given symbol, resolution, from and to
-func GetResponse(symbol, resolution string, from, to int64) (finnhub.CryptoCandles, error) {
-	cfg := finnhub.NewConfiguration()
-	cfg.AddDefaultHeader("X-Finnhub-Token", os.Getenv("TOKEN"))
-	finnhubClient := finnhub.NewAPIClient(cfg).DefaultApi
+// A CandleProvider is a source of OHLCV history
+// It retrieves the candles of the given symbol and resolution between from and to, sorted by timestamp
+type CandleProvider interface {
+	GetCandles(symbol, resolution string, from, to int64) ([]data.Candle, error)
+}
 
-	res, _, err := finnhubClient.CryptoCandles(context.Background()).Symbol(symbol).Resolution(resolution).From(from).To(to).Execute()
-	if err != nil {
-		return finnhub.CryptoCandles{}, err
-	}
-	return res, nil
+// Retrieve the candles for the given symbol, resolution, from and to using the default finnhub provider
+// If baseURL is not empty the requests are sent to it instead of finnhub (e.g. the stub server, see stub.NewServer)
+func GetResponse(baseURL, symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	provider := NewFinnhubProvider()
+	provider.BaseURL = baseURL
+	return provider.GetCandles(symbol, resolution, from, to)
 }
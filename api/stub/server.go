@@ -0,0 +1,184 @@
+package stub
+
+// A local stand-in for the finnhub API, used to run the whole pipeline without the internet
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/data"
+)
+
+// Serve the /crypto/candle and /stock/candle endpoints from the fixture files inside Dir
+// The responses have the exact finnhub JSON shape so the real SDK can be pointed at the server
+//
+// For a symbol like BINANCE:BTCUSDT and resolution 30 the fixtures are looked up in this order:
+//
+//	BINANCE_BTCUSDT_30.json, BINANCE_BTCUSDT_30.csv  used as they are
+//	BINANCE_BTCUSDT.json, BINANCE_BTCUSDT.csv        resampled to the resolution
+//	BINANCE_BTCUSDT_5.json, BINANCE_BTCUSDT_15.csv...  the coarsest finer resolution, resampled
+//
+// So a single intraday fixture (e.g. BINANCE_BTCUSDT_30.json) serves the daily history of a backtest too
+// The fixture formats are the ones supported by api.LoadCandles
+// If Token is set the requests must send it in the X-Finnhub-Token header
+type Server struct {
+	Dir   string
+	Token string
+}
+
+// The finnhub candle response
+type candleResponse struct {
+	C []float32 `json:"c"`
+	H []float32 `json:"h"`
+	L []float32 `json:"l"`
+	O []float32 `json:"o"`
+	T []int64   `json:"t"`
+	V []float32 `json:"v"`
+	S string    `json:"s"`
+}
+
+// Start a local server on a random port serving the fixtures inside dir
+// The URL to use as the finnhub base URL is server.URL + "/api/v1"
+func NewServer(dir string) *httptest.Server {
+	return httptest.NewServer(&Server{Dir: dir})
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/crypto/candle") && !strings.HasSuffix(r.URL.Path, "/stock/candle") {
+		writeError(w, http.StatusNotFound, "endpoint not found")
+		return
+	}
+	if server.Token != "" && r.Header.Get("X-Finnhub-Token") != server.Token {
+		writeError(w, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+
+	query := r.URL.Query()
+	symbol := query.Get("symbol")
+	resolution := query.Get("resolution")
+	from, errFrom := strconv.ParseInt(query.Get("from"), 10, 64)
+	to, errTo := strconv.ParseInt(query.Get("to"), 10, 64)
+	if symbol == "" || resolution == "" || errFrom != nil || errTo != nil {
+		writeError(w, http.StatusUnprocessableEntity, "Wrong symbol, resolution, from or to")
+		return
+	}
+
+	candles, err := server.candles(symbol, resolution, from, to)
+	if errors.Is(err, os.ErrNotExist) {
+		writeJSON(w, http.StatusOK, candleResponse{S: "no_data"})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toResponse(candles))
+}
+
+// Find the fixture for the symbol and resolution and retrieve the candles between from and to
+func (server *Server) candles(symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	name := api.FileName(symbol)
+
+	for _, ext := range []string{".json", ".csv"} {
+		path := filepath.Join(server.Dir, name+"_"+resolution+ext)
+		if _, err := os.Stat(path); err == nil {
+			candles, err := api.LoadCandles(path)
+			if err != nil {
+				return nil, err
+			}
+			return filter(candles, from, to), nil
+		}
+	}
+
+	for _, ext := range []string{".json", ".csv"} {
+		path := filepath.Join(server.Dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			provider := api.FileProvider{Path: path}
+			return provider.GetCandles(symbol, resolution, from, to)
+		}
+	}
+
+	if path := server.finerFixture(name, resolution); path != "" {
+		provider := api.FileProvider{Path: path}
+		return provider.GetCandles(symbol, resolution, from, to)
+	}
+
+	return nil, os.ErrNotExist
+}
+
+// Find the fixture of the symbol with the coarsest resolution that is finer than the requested one and divides it
+// It is empty if there is none
+func (server *Server) finerFixture(name, resolution string) string {
+	period, err := api.ResolutionSeconds(resolution)
+	if err != nil {
+		return ""
+	}
+	matches, err := filepath.Glob(filepath.Join(server.Dir, name+"_*"))
+	if err != nil {
+		return ""
+	}
+
+	var best string
+	var bestPeriod int64
+	for _, path := range matches {
+		ext := filepath.Ext(path)
+		if ext != ".json" && ext != ".csv" {
+			continue
+		}
+		fixturePeriod, err := api.ResolutionSeconds(strings.TrimPrefix(strings.TrimSuffix(filepath.Base(path), ext), name+"_"))
+		if err != nil || fixturePeriod >= period || period%fixturePeriod != 0 {
+			continue
+		}
+		if fixturePeriod > bestPeriod {
+			best, bestPeriod = path, fixturePeriod
+		}
+	}
+	return best
+}
+
+func filter(candles []data.Candle, from, to int64) []data.Candle {
+	var res []data.Candle
+	for _, candle := range candles {
+		if candle.Timestamp >= from && candle.Timestamp <= to {
+			res = append(res, candle)
+		}
+	}
+	return res
+}
+
+// Build the finnhub response, a range without candles has the no_data status
+func toResponse(candles []data.Candle) candleResponse {
+	if len(candles) == 0 {
+		return candleResponse{S: "no_data"}
+	}
+
+	res := candleResponse{S: "ok"}
+	for _, candle := range candles {
+		res.C = append(res.C, candle.Close)
+		res.H = append(res.H, candle.High)
+		res.L = append(res.L, candle.Low)
+		res.O = append(res.O, candle.Open)
+		res.T = append(res.T, candle.Timestamp)
+		res.V = append(res.V, candle.Volume)
+	}
+	return res
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Write an error the way finnhub does
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
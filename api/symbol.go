@@ -0,0 +1,44 @@
+package api
+
+import "strings"
+
+// The symbol of an instrument, e.g. BINANCE:BTCUSDT or AAPL
+// Crypto pairs have the exchange prefix, equities don't
+type Symbol struct {
+	Exchange, Ticker string
+}
+
+// Split a symbol in the form EXCHANGE:TICKER, the exchange is optional
+func ParseSymbol(symbol string) Symbol {
+	if exchange, ticker, found := strings.Cut(symbol, ":"); found {
+		return Symbol{Exchange: strings.ToUpper(exchange), Ticker: strings.ToUpper(ticker)}
+	}
+	return Symbol{Ticker: strings.ToUpper(symbol)}
+}
+
+func (symbol Symbol) String() string {
+	if symbol.Exchange == "" {
+		return symbol.Ticker
+	}
+	return symbol.Exchange + ":" + symbol.Ticker
+}
+
+// Check if the symbol is a crypto pair, finnhub serves them from the crypto endpoints
+func (symbol Symbol) IsCrypto() bool {
+	return symbol.Exchange != ""
+}
+
+// Retrieve the base currency of a crypto pair removing the quote currency, e.g. BINANCE:ETHUSDT -> ETH
+func (symbol Symbol) Base() string {
+	for _, quote := range []string{"USDT", "BUSD", "USDC", "USD", "EUR", "BTC"} {
+		if base := strings.TrimSuffix(symbol.Ticker, quote); base != symbol.Ticker && base != "" {
+			return base
+		}
+	}
+	return symbol.Ticker
+}
+
+// Retrieve the symbol in a form usable in file names, e.g. BINANCE:BTCUSDT -> BINANCE_BTCUSDT
+func FileName(symbol string) string {
+	return strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(symbol)
+}
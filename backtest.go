@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/backtest"
+	"github.com/frappaf/tradingBot/bot"
+	"github.com/frappaf/tradingBot/report"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+// The settings of a backtest read from the flags, shared by the backtest and the report commands
+// The allocations are nil unless a portfolio is tested
+type backtestSettings struct {
+	provider    api.CandleProvider
+	config      backtest.Config
+	allocations map[string]float32
+	monteCarlo  backtest.MonteCarlo
+}
+
+// Add the flags of a backtest to the flag set
+// The returned function retrieves the settings after the parsing
+func addBacktestFlags(flags *flag.FlagSet) func() (backtestSettings, error) {
+	data := addDataFlags(flags)
+	symbol := addSymbolFlag(flags)
+	portfolioFlag := flags.String("portfolio", "", portfolioUsage)
+	paramsFlag := flags.String("params", "", paramsUsage)
+	account := addAccountFlags(flags)
+	replay := addReplayFlags(flags, "Start of the replay, the strategy knows only the days before it", "End of the replay")
+	risk := addRiskFlags(flags)
+	benchmarkRuns := flags.Int("benchmark-runs", 1000, "Number of random-entry runs of the benchmark")
+	seed := flags.Int64("seed", 1, "Seed of the random-entry benchmark and of the Monte Carlo")
+	monteCarloFlags := addMonteCarloFlags(flags)
+
+	return func() (backtestSettings, error) {
+		var settings backtestSettings
+		from, balance, err := account()
+		if err != nil {
+			return settings, err
+		}
+		replaySettings, err := replay()
+		if err != nil {
+			return settings, err
+		}
+		params, err := strategy.ParseParams(*paramsFlag)
+		if err != nil {
+			return settings, err
+		}
+		if settings.monteCarlo, err = monteCarloFlags(); err != nil {
+			return settings, err
+		}
+		settings.monteCarlo.Seed = *seed
+		if *portfolioFlag != "" {
+			if settings.allocations, err = parseAllocations(*portfolioFlag); err != nil {
+				return settings, err
+			}
+		}
+
+		settings.provider = data()
+		settings.config = backtest.Config{
+			Symbol:         *symbol,
+			From:           from,
+			To:             replaySettings.start,
+			End:            replaySettings.end,
+			Resolution:     replaySettings.resolution,
+			InitialBalance: balance,
+			Params:         params,
+
+			BenchmarkRuns: *benchmarkRuns,
+			Seed:          *seed,
+		}
+		return settings, risk(&settings.config)
+	}
+}
+
+// Run the backtest and the Monte Carlo if enabled, then print their results
+func (settings backtestSettings) run(outputs outputSettings) (backtest.Result, error) {
+	settings.config.Logger = outputs.logger()
+	var result backtest.Result
+	var err error
+	if settings.allocations != nil {
+		result, err = backtest.RunPortfolioBacktest(settings.provider, settings.allocations, settings.config)
+	} else {
+		result, err = backtest.RunBacktest(settings.provider, settings.config)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.Print()
+	if settings.monteCarlo.Runs > 0 {
+		if result.MonteCarlo, err = backtest.RunMonteCarlo(result, settings.monteCarlo); err != nil {
+			return result, err
+		}
+		result.MonteCarlo.Print()
+	}
+	return result, nil
+}
+
+// Replay the history and print the metrics, optionally writing the trades, the equity, the Monte Carlo bands and the report
+func runBacktest(flags *flag.FlagSet, args []string) error {
+	settingsFlags := addBacktestFlags(flags)
+	outputFlags := addOutputFlags(flags, ".")
+	journalFlag := addJournalFlag(flags)
+	reportFile := flags.String("report", "", "HTML file receiving the report with the chart of the candles, the areas, the trades and the equity, empty to disable it")
+	equityFile := flags.String("equity", "", "CSV file receiving the equity curve with the drawdown and the high-water mark, empty to disable it")
+	monteCarloFile := flags.String("monte-carlo-out", "", "CSV file receiving the Monte Carlo equity percentile bands after every trade, empty to disable it")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	settings, err := settingsFlags()
+	if err != nil {
+		return err
+	}
+	outputs, err := outputFlags()
+	if err != nil {
+		return err
+	}
+	journal, err := journalFlag(outputs)
+	if err != nil {
+		return err
+	}
+	if *monteCarloFile != "" && settings.monteCarlo.Runs == 0 {
+		return fmt.Errorf("--monte-carlo-out NEEDS --monte-carlo")
+	}
+
+	result, err := settings.run(outputs)
+	if err != nil {
+		return err
+	}
+
+	if journal != nil {
+		if err := journal.Write(result.Trades); err != nil {
+			return err
+		}
+	}
+	if *equityFile != "" {
+		if err := backtest.SaveEquity(outputs.path(*equityFile), result.Equity); err != nil {
+			return err
+		}
+	}
+	if *monteCarloFile != "" {
+		if err := backtest.SaveMonteCarlo(outputs.path(*monteCarloFile), result.MonteCarlo); err != nil {
+			return err
+		}
+	}
+	if *reportFile != "" {
+		if err := report.Save(outputs.path(*reportFile), result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The files written by the report command inside the output directory
+const (
+	reportPage       = "report.html"
+	reportTrades     = "trades.csv"
+	reportEquity     = "equity.csv"
+	reportMonteCarlo = "monte-carlo.csv"
+)
+
+// Run a backtest of a single symbol and write all its outputs in the output directory
+func runReport(flags *flag.FlagSet, args []string) error {
+	settingsFlags := addBacktestFlags(flags)
+	outputFlags := addOutputFlags(flags, "report")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	settings, err := settingsFlags()
+	if err != nil {
+		return err
+	}
+	if settings.allocations != nil {
+		return fmt.Errorf("THE REPORT DRAWS A SINGLE SYMBOL, USE --symbol INSTEAD OF --portfolio")
+	}
+	outputs, err := outputFlags()
+	if err != nil {
+		return err
+	}
+
+	result, err := settings.run(outputs)
+	if err != nil {
+		return err
+	}
+
+	if err := bot.SaveTrades(outputs.path(reportTrades), result.Trades); err != nil {
+		return err
+	}
+	if err := backtest.SaveEquity(outputs.path(reportEquity), result.Equity); err != nil {
+		return err
+	}
+	if result.MonteCarlo.Runs > 0 {
+		if err := backtest.SaveMonteCarlo(outputs.path(reportMonteCarlo), result.MonteCarlo); err != nil {
+			return err
+		}
+	}
+	if err := report.Save(outputs.path(reportPage), result); err != nil {
+		return err
+	}
+	fmt.Printf("Report written to %v\n", outputs.path(reportPage))
+	return nil
+}
@@ -1,32 +1,223 @@
 package backtest
 
 import (
+	"context"
 	"time"
 
 	"github.com/frappaf/tradingBot/api"
 	"github.com/frappaf/tradingBot/bot"
+	"github.com/frappaf/tradingBot/broker"
 	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/sizing"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
 )
 
-func RunBacktest(from, to int64, resolution string) {
-	btcBot := bot.Bot{}
-	err := btcBot.Initialize(10000.0, from, to)
+// The settings of a backtest
+// The bot is initialized with the daily history from From to To, then the candles of the Resolution are replayed from To until End (now if 0)
+// The orders are filled by a paper broker with the InitialBalance (10000 if 0) applying the Costs
+// Params tunes the breakout strategy (see strategy.Params), the symbols of a portfolio share them
+// BothHit decides the exit when a candle touches both the stopLoss and the takeProfit
+// Sizer and MaxExposure decide the units of the positions, Trailing and BreakEven move their stopLoss
+// and Ladder scales them out at the successive take profits (see bot.Bot)
+// CloseAtEnd closes the positions still opened at the close of the last candle, so their trades and costs are in the result
+// BenchmarkRuns is the number of random-entry runs of the benchmark (1000 if 0, negative to skip the benchmark), Seed makes them reproducible
+// Logger receives the logs of the bots, if nil they go to the standard output (utils.Discard runs them quietly)
+// Strategy builds the strategy of every symbol, if nil it is the breakout with the Params
+type Config struct {
+	Symbol         string
+	From, To, End  int64
+	Resolution     string
+	InitialBalance float32
+	Params         strategy.Params
+	Costs          broker.Costs
+	BothHit        bot.BothHitRule
+	Sizer          sizing.Sizer
+	MaxExposure    float32
+	Trailing       bot.TrailingStop
+	BreakEven      float32
+	Ladder         []float32
+	CloseAtEnd     bool
+
+	BenchmarkRuns int
+	Seed          int64
+	Logger        *utils.Logger
+	Strategy      func(symbol string) strategy.Strategy
+}
+
+// Build the strategy of the symbol
+func (config Config) strategy(symbol string) strategy.Strategy {
+	if config.Strategy != nil {
+		return config.Strategy(symbol)
+	}
+	return &strategy.Breakout{Params: config.Params}
+}
+
+// Apply the settings of the config to a bot
+func (config Config) configure(backtestBot *bot.Bot) {
+	backtestBot.BothHit = config.BothHit
+	backtestBot.Sizer = config.Sizer
+	backtestBot.MaxExposure = config.MaxExposure
+	backtestBot.Trailing = config.Trailing
+	backtestBot.BreakEven = config.BreakEven
+	backtestBot.Ladder = config.Ladder
+	backtestBot.SetLogger(config.Logger)
+}
+
+const defaultInitialBalance float32 = 10000.0
+
+// Retrieve the initial balance and the end of the replay applying the defaults
+func (config Config) bounds() (float32, int64) {
+	balance, end := config.InitialBalance, config.End
+	if balance <= 0 {
+		balance = defaultInitialBalance
+	}
+	if end == 0 {
+		end = time.Now().Unix()
+	}
+	return balance, end
+}
+
+// Fetch the candles to replay, from To until End excluded
+func (config Config) replayCandles(provider api.CandleProvider, symbol string, end int64) ([]data.Candle, error) {
+	candles, err := provider.GetCandles(symbol, config.Resolution, config.To, end)
+	if err != nil {
+		return nil, err
+	}
+	for len(candles) > 0 && candles[len(candles)-1].Timestamp >= end {
+		candles = candles[:len(candles)-1]
+	}
+	return candles, nil
+}
+
+// Compute the benchmark of the result unless it is disabled
+func (config Config) benchmark(result *Result, candles map[string][]data.Candle, allocations map[string]float32, period int64) {
+	if config.BenchmarkRuns < 0 {
+		return
+	}
+	result.Benchmark = newBenchmark(*result, candles, allocations, period, config.Costs, config.BenchmarkRuns, config.Seed)
+}
+
+// Initialize the bot with the daily history of the symbol until to
+// Then replay the candles of the given resolution from to until now calling the Predict
+// and retrieve the trades and the metrics of the run
+// The run is point-in-time: it fails with a LookAheadError if the bot accesses data after the close of the current bar
+func RunBacktest(provider api.CandleProvider, config Config) (Result, error) {
+	period, err := api.ResolutionSeconds(config.Resolution)
+	if err != nil {
+		return Result{}, err
+	}
+
+	initialBalance, end := config.bounds()
+	paper := broker.NewPaper(initialBalance)
+	paper.Costs = config.Costs
+
+	guard := newLookAheadGuard(provider, config.To)
+	backtestBot := bot.Bot{Strategy: config.strategy(config.Symbol), Provider: guard, Broker: paper}
+	config.configure(&backtestBot)
+	if err := backtestBot.Initialize(config.Symbol, initialBalance, config.From, config.To); err != nil {
+		return Result{}, err
+	}
+	if err := guard.checkHistory(config.Symbol, backtestBot.Strategy); err != nil {
+		return Result{}, err
+	}
+
+	candles, err := config.replayCandles(provider, config.Symbol, end)
 	if err != nil {
-		panic(err)
+		return Result{}, err
+	}
+
+	curve := newEquityCurve(paper)
+	for i, candle := range candles {
+		guard.advance(candle.Timestamp + period)
+		backtestBot.Predict(context.Background(), candle)
+		if err := guard.checkHistory(config.Symbol, backtestBot.Strategy); err != nil {
+			return Result{}, err
+		}
+		if config.CloseAtEnd && i == len(candles)-1 {
+			backtestBot.Liquidate(candle.Close, candle.Timestamp)
+		}
+		curve.record(candle.Timestamp, map[string]data.Candle{config.Symbol: candle})
 	}
 
-	res, err := api.GetResponse("BINANCE:BTCUSDT", resolution, to, time.Now().Unix())
+	result := newResult(initialBalance, backtestBot.Trades, curve.points, period)
+	config.benchmark(&result, map[string][]data.Candle{config.Symbol: candles}, map[string]float32{config.Symbol: 1}, period)
+	result.Symbol = config.Symbol
+	result.Candles = candles
+	result.Strategy = backtestBot.Strategy
+	return result, nil
+}
 
-	for i := 0; i < len(res.GetC()); i++ {
-		o := res.GetO()[i]
-		c := res.GetC()[i]
-		h := res.GetH()[i]
-		l := res.GetL()[i]
-		v := res.GetV()[i]
-		t := res.GetT()[i]
+// Initialize a portfolio with a bot for every symbol of the allocations map sharing the same balance
+// Then replay the candles of all the symbols from to until now (see bot.Portfolio.Replay)
+// The Symbol of the config is ignored, all the bots use its Params (or its Strategy), the result contains the trades of all the bots
+// Like RunBacktest the run is point-in-time
+func RunPortfolioBacktest(provider api.CandleProvider, allocations map[string]float32, config Config) (Result, error) {
+	period, err := api.ResolutionSeconds(config.Resolution)
+	if err != nil {
+		return Result{}, err
+	}
 
-		candle := data.Candle{Open: o, Close: c, High: h, Low: l, Volume: v, Timestamp: t}
-		btcBot.Predict(candle, time.Unix(t, 0))
+	initialBalance, end := config.bounds()
+	guard := newLookAheadGuard(provider, config.To)
+	portfolio, err := bot.NewPortfolio(guard, config.strategy, initialBalance, allocations, config.From, config.To)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, symbolBot := range portfolio.Bots {
+		if err := guard.checkHistory(symbolBot.Symbol, symbolBot.Strategy); err != nil {
+			return Result{}, err
+		}
+	}
+	portfolio.Broker.Costs = config.Costs
+	for _, symbolBot := range portfolio.Bots {
+		config.configure(symbolBot)
+	}
+
+	candles := make(map[string][]data.Candle, len(allocations))
+	for symbol := range allocations {
+		if candles[symbol], err = config.replayCandles(provider, symbol, end); err != nil {
+			return Result{}, err
+		}
+	}
+
+	var last int64
+	for _, symbolCandles := range candles {
+		if len(symbolCandles) > 0 && symbolCandles[len(symbolCandles)-1].Timestamp > last {
+			last = symbolCandles[len(symbolCandles)-1].Timestamp
+		}
+	}
+
+	curve := newEquityCurve(portfolio.Broker)
+	portfolio.BeforeStep = func(timestamp int64, _ map[string]data.Candle) {
+		guard.advance(timestamp + period)
+	}
+	portfolio.AfterStep = func(timestamp int64, stepCandles map[string]data.Candle) {
+		for _, symbolBot := range portfolio.Bots {
+			guard.checkHistory(symbolBot.Symbol, symbolBot.Strategy)
+		}
+		if config.CloseAtEnd && timestamp == last {
+			for _, symbolBot := range portfolio.Bots {
+				if symbolCandles := candles[symbolBot.Symbol]; len(symbolCandles) > 0 {
+					symbolBot.Liquidate(symbolCandles[len(symbolCandles)-1].Close, timestamp)
+				}
+			}
+		}
+		curve.record(timestamp, stepCandles)
+	}
+	portfolio.Replay(context.Background(), candles)
+
+	//The Replay can't be stopped, the first violation is reported at the end
+	if err := guard.Err(); err != nil {
+		return Result{}, err
+	}
+	portfolio.Print()
 
+	var trades []bot.Trade
+	for _, symbolBot := range portfolio.Bots {
+		trades = append(trades, symbolBot.Trades...)
 	}
+	result := newResult(initialBalance, trades, curve.points, period)
+	config.benchmark(&result, candles, allocations, period)
+	return result, nil
 }
@@ -0,0 +1,122 @@
+package backtest
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/frappaf/tradingBot/bot"
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/data"
+)
+
+const defaultBenchmarkRuns = 1000
+
+// The baselines of a backtest on the same candles
+// BuyAndHoldReturn buys every symbol with its allocation on the first candle and sells it on the last one, paying the taker fee
+// The random-entry runs replay the trades of the strategy entering at random candles, with the same symbol, side and holding time
+// and the same notional and costs as fractions of the equity before the trade, and compound their returns like the strategy does
+// The trades holding longer than the candles of their symbol are left out, RandomTrades counts the replayed ones
+// RandomReturns are the total returns of the runs sorted in ascending order, they can't be below -100%
+// Percentile is the fraction of the random runs that did worse than the strategy
+type Benchmark struct {
+	BuyAndHoldReturn         float32
+	RandomMeanReturn         float32
+	RandomReturns            []float32
+	ExcessReturn             float32 //Over the buy and hold
+	ExcessOverRandom         float32 //Over the mean of the random runs
+	Percentile               float32
+	RandomRuns, RandomTrades int
+}
+
+// Compute the benchmarks of the trades of a backtest on the candles of every symbol
+func newBenchmark(result Result, candles map[string][]data.Candle, allocations map[string]float32, period int64, costs broker.Costs, runs int, seed int64) Benchmark {
+	if runs <= 0 {
+		runs = defaultBenchmarkRuns
+	}
+	benchmark := Benchmark{RandomRuns: runs, RandomTrades: len(result.Trades)}
+
+	//Buy and hold
+	var final float32
+	var invested float32
+	for symbol, allocation := range allocations {
+		symbolCandles := candles[symbol]
+		if len(symbolCandles) == 0 {
+			continue
+		}
+		capital := result.InitialBalance * allocation
+		entry, exit := symbolCandles[0].Open, symbolCandles[len(symbolCandles)-1].Close
+		units := capital * (1 - costs.TakerFee) / entry
+		final += units * exit * (1 - costs.TakerFee)
+		invested += capital
+	}
+	final += result.InitialBalance - invested
+	benchmark.BuyAndHoldReturn = final/result.InitialBalance - 1
+	benchmark.ExcessReturn = result.TotalReturn - benchmark.BuyAndHoldReturn
+
+	//Random entries
+	trades := randomTrades(result, candles, period)
+	benchmark.RandomTrades = len(trades)
+	random := rand.New(rand.NewSource(seed))
+	benchmark.RandomReturns = make([]float32, 0, runs)
+	var sum float32
+	for run := 0; run < runs; run++ {
+		equity := 1.0
+		for _, trade := range trades {
+			equity *= 1 + trade.randomReturn(random, candles[trade.Symbol])
+			if equity <= 0 {
+				equity = 0
+				break
+			}
+		}
+		runReturn := float32(equity - 1)
+		benchmark.RandomReturns = append(benchmark.RandomReturns, runReturn)
+		sum += runReturn
+	}
+	sort.Slice(benchmark.RandomReturns, func(i, j int) bool { return benchmark.RandomReturns[i] < benchmark.RandomReturns[j] })
+
+	benchmark.RandomMeanReturn = sum / float32(runs)
+	benchmark.ExcessOverRandom = result.TotalReturn - benchmark.RandomMeanReturn
+	worse := sort.Search(len(benchmark.RandomReturns), func(i int) bool { return benchmark.RandomReturns[i] >= result.TotalReturn })
+	benchmark.Percentile = float32(worse) / float32(runs)
+
+	return benchmark
+}
+
+// A trade of the strategy replayed by the random-entry runs
+// Hold is its number of candles, Exposure its notional and Costs its fees and funding as fractions of the equity before it
+type randomTrade struct {
+	bot.Trade
+	Hold            int
+	Exposure, Costs float64
+}
+
+// Retrieve the trades of the result that fit in the candles of their symbol, the trades are sorted by exit
+func randomTrades(result Result, candles map[string][]data.Candle, period int64) []randomTrade {
+	var trades []randomTrade
+	balance := float64(result.InitialBalance)
+	for _, trade := range result.Trades {
+		if balance <= 0 {
+			break
+		}
+
+		hold := int((trade.ExitTimestamp - trade.EntryTimestamp) / period)
+		if hold < len(candles[trade.Symbol]) {
+			trades = append(trades, randomTrade{
+				Trade:    trade,
+				Hold:     hold,
+				Exposure: float64(trade.EntryPrice*trade.Units) / balance,
+				Costs:    float64(trade.EntryFee+trade.ExitFee+trade.Funding) / balance,
+			})
+		}
+		balance += float64(trade.NetPL)
+	}
+	return trades
+}
+
+// The return on the equity of the trade entering at the close of a random candle and holding it for the same number of candles
+func (trade randomTrade) randomReturn(random *rand.Rand, candles []data.Candle) float64 {
+	start := random.Intn(len(candles) - trade.Hold)
+	entry, exit := float64(candles[start].Close), float64(candles[start+trade.Hold].Close)
+
+	return float64(trade.Side)*(exit-entry)/entry*trade.Exposure - trade.Costs
+}
@@ -0,0 +1,42 @@
+package backtest
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/data"
+)
+
+// The candles of a symbol loaded once and shared by many backtests
+// It is immutable after the loading, so the backtests can read it concurrently
+// The retrieved slices can't grow over the candles that follow them
+type Dataset struct {
+	Symbol  string
+	candles map[string][]data.Candle //By resolution
+}
+
+// Load the candles of the symbol between from and to for every resolution
+func LoadDataset(provider api.CandleProvider, symbol string, from, to int64, resolutions ...string) (*Dataset, error) {
+	dataset := &Dataset{Symbol: symbol, candles: make(map[string][]data.Candle, len(resolutions))}
+	for _, resolution := range resolutions {
+		candles, err := provider.GetCandles(symbol, resolution, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("loading the %v candles: %w", resolution, err)
+		}
+		dataset.candles[resolution] = candles
+	}
+	return dataset, nil
+}
+
+// Retrieve the loaded candles between from and to
+func (dataset *Dataset) GetCandles(symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	candles, ok := dataset.candles[resolution]
+	if symbol != dataset.Symbol || !ok {
+		return nil, fmt.Errorf("THE DATASET DOESN'T CONTAIN THE %v CANDLES OF %v", resolution, symbol)
+	}
+
+	start := sort.Search(len(candles), func(i int) bool { return candles[i].Timestamp >= from })
+	end := sort.Search(len(candles), func(i int) bool { return candles[i].Timestamp > to })
+	return candles[start:end:end], nil
+}
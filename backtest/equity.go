@@ -0,0 +1,119 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/data"
+)
+
+// The equity of the account at the close of a candle
+// Equity is the Balance plus the unrealised profit/loss, the HighWaterMark is the highest equity so far
+// and the Drawdown is the loss from the HighWaterMark as a fraction of it
+// Exposed is true if a position was opened at that time
+type EquityPoint struct {
+	Timestamp       int64
+	Balance, Equity float32
+	HighWaterMark   float32
+	Drawdown        float32
+	Exposed         bool
+}
+
+// Records the mark-to-market equity of a paper broker: the balance plus the unrealised profit/loss
+// of the opened positions valued at the last close of their symbol
+type equityCurve struct {
+	paper         *broker.Paper
+	closes        map[string]float32
+	highWaterMark float32
+	points        []EquityPoint
+}
+
+func newEquityCurve(paper *broker.Paper) *equityCurve {
+	return &equityCurve{paper: paper, closes: make(map[string]float32), highWaterMark: paper.Balance()}
+}
+
+// Update the last closes with the candles and record the equity at the timestamp
+func (curve *equityCurve) record(timestamp int64, candles map[string]data.Candle) {
+	for symbol, candle := range candles {
+		curve.closes[symbol] = candle.Close
+	}
+
+	point := EquityPoint{Timestamp: timestamp, Balance: curve.paper.Balance()}
+	point.Equity = point.Balance
+	positions := curve.paper.Positions()
+	for _, position := range positions {
+		if price, ok := curve.closes[position.Symbol]; ok {
+			point.Equity += float32(position.Side) * (price - position.EntryPrice) * position.Units
+		}
+	}
+	point.Exposed = len(positions) > 0
+
+	if point.Equity > curve.highWaterMark {
+		curve.highWaterMark = point.Equity
+	}
+	point.HighWaterMark = curve.highWaterMark
+	if curve.highWaterMark > 0 {
+		point.Drawdown = 1 - point.Equity/curve.highWaterMark
+	}
+
+	curve.points = append(curve.points, point)
+}
+
+// Compute again the high-water mark and the drawdown of the points, starting from the initial balance
+// Used to join the curves of consecutive runs
+func restateDrawdown(points []EquityPoint, initialBalance float32) {
+	highWaterMark := initialBalance
+	for i := range points {
+		if points[i].Equity > highWaterMark {
+			highWaterMark = points[i].Equity
+		}
+		points[i].HighWaterMark = highWaterMark
+		points[i].Drawdown = 0
+		if highWaterMark > 0 {
+			points[i].Drawdown = 1 - points[i].Equity/highWaterMark
+		}
+	}
+}
+
+// Write the equity curve as CSV with a header
+func WriteEquityCSV(w io.Writer, points []EquityPoint) error {
+	float := func(value float32) string { return strconv.FormatFloat(float64(value), 'f', -1, 32) }
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"timestamp", "time", "balance", "equity", "high_water_mark", "drawdown", "exposed"}); err != nil {
+		return err
+	}
+	for _, point := range points {
+		record := []string{
+			strconv.FormatInt(point.Timestamp, 10),
+			time.Unix(point.Timestamp, 0).UTC().Format(time.RFC3339),
+			float(point.Balance),
+			float(point.Equity),
+			float(point.HighWaterMark),
+			float(point.Drawdown),
+			strconv.FormatBool(point.Exposed),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Write the equity curve to a CSV file
+func SaveEquity(path string, points []EquityPoint) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteEquityCSV(file, points); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,98 @@
+package backtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+const day int64 = 24 * 60 * 60
+
+// The error of a backtest accessing data that was not available at the time of the current bar
+type LookAheadError struct {
+	Now    int64 //The close of the current bar
+	Reason string
+}
+
+func (err *LookAheadError) Error() string {
+	return fmt.Sprintf("LOOK-AHEAD AT %v: %v", time.Unix(err.Now, 0).UTC().Format(time.RFC3339), err.Reason)
+}
+
+// Strategies can expose the daily candles they know to let the guard check them, like the Breakout does
+type historyStrategy interface{ History() []data.Candle }
+
+// The harness of the point-in-time backtests
+// The engine moves Now to the close of every bar before predicting it
+// The bots fetch their candles through the guard, which refuses the requests ending after Now,
+// and after every bar the guard checks that the history of the strategies contains only the days closed before Now
+// Err retrieves the first violation
+type lookAheadGuard struct {
+	Source api.CandleProvider
+
+	mu  sync.Mutex
+	now int64
+	err error
+}
+
+func newLookAheadGuard(source api.CandleProvider, now int64) *lookAheadGuard {
+	return &lookAheadGuard{Source: source, now: now}
+}
+
+// Move the time of the guard
+func (guard *lookAheadGuard) advance(now int64) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	guard.now = now
+}
+
+// Retrieve the candles from the source if the range doesn't end after now
+func (guard *lookAheadGuard) GetCandles(symbol, resolution string, from, to int64) ([]data.Candle, error) {
+	guard.mu.Lock()
+	now := guard.now
+	guard.mu.Unlock()
+
+	if to > now {
+		return nil, guard.fail(&LookAheadError{Now: now, Reason: fmt.Sprintf("%v candles requested until %v", symbol, time.Unix(to, 0).UTC().Format(time.RFC3339))})
+	}
+	return guard.Source.GetCandles(symbol, resolution, from, to)
+}
+
+// Check that the strategy only knows the days closed at now
+func (guard *lookAheadGuard) checkHistory(symbol string, strategy strategy.Strategy) error {
+	known, ok := strategy.(historyStrategy)
+	if !ok {
+		return guard.Err()
+	}
+
+	guard.mu.Lock()
+	now := guard.now
+	guard.mu.Unlock()
+
+	history := known.History()
+	if len(history) > 0 && history[len(history)-1].Timestamp+day > now {
+		last := history[len(history)-1]
+		return guard.fail(&LookAheadError{Now: now, Reason: fmt.Sprintf("the %v history contains the day %v that is not closed", symbol, time.Unix(last.Timestamp, 0).UTC().Format("2006-01-02"))})
+	}
+	return guard.Err()
+}
+
+// Record the violation if it's the first one
+func (guard *lookAheadGuard) fail(err error) error {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	if guard.err == nil {
+		guard.err = err
+	}
+	return err
+}
+
+// Retrieve the first violation
+func (guard *lookAheadGuard) Err() error {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	return guard.err
+}
@@ -0,0 +1,90 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// A strategy that adds the day of every candle to its history before the day is closed
+type peekingStrategy struct {
+	history []data.Candle
+}
+
+func (peeking *peekingStrategy) Initialize(history []data.Candle) {
+	peeking.history = history
+}
+
+func (peeking *peekingStrategy) OnCandle(ctx context.Context, candle data.Candle, position int8) []strategy.Signal {
+	start := candle.Timestamp - candle.Timestamp%day
+	if n := len(peeking.history); n == 0 || peeking.history[n-1].Timestamp < start {
+		candle.Timestamp = start
+		peeking.history = append(peeking.history, candle)
+	}
+	return nil
+}
+
+func (peeking *peekingStrategy) History() []data.Candle {
+	return peeking.history
+}
+
+func lookAheadConfig(t *testing.T) Config {
+	return Config{
+		Symbol:        "BINANCE:BTCUSDT",
+		From:          date(t, "2021-11-01"),
+		To:            date(t, "2022-01-01"),
+		End:           date(t, "2022-01-10"),
+		Resolution:    "30",
+		BenchmarkRuns: -1,
+		Logger:        utils.Discard,
+		Strategy:      func(string) strategy.Strategy { return &peekingStrategy{} },
+	}
+}
+
+func TestLookAheadGuardRejectsUnclosedDay(t *testing.T) {
+	provider := &api.FileProvider{Path: "../testdata/{symbol}_30.json"}
+
+	_, err := RunBacktest(provider, lookAheadConfig(t))
+	var lookAhead *LookAheadError
+	if !errors.As(err, &lookAhead) {
+		t.Fatalf("expected a LookAheadError, got %v", err)
+	}
+
+	allocations := map[string]float32{"BINANCE:BTCUSDT": 0.5, "BINANCE:ETHUSDT": 0.5}
+	_, err = RunPortfolioBacktest(provider, allocations, lookAheadConfig(t))
+	if !errors.As(err, &lookAhead) {
+		t.Fatalf("expected a LookAheadError from the portfolio, got %v", err)
+	}
+}
+
+func TestLookAheadGuardRejectsFutureCandles(t *testing.T) {
+	provider := &api.FileProvider{Path: "../testdata/BINANCE_BTCUSDT_30.json"}
+	now := date(t, "2022-01-01")
+	guard := newLookAheadGuard(provider, now)
+
+	if _, err := guard.GetCandles("BINANCE:BTCUSDT", "30", now-day, now); err != nil {
+		t.Fatalf("the candles until now must be allowed, got %v", err)
+	}
+
+	_, err := guard.GetCandles("BINANCE:BTCUSDT", "30", now-day, now+1)
+	var lookAhead *LookAheadError
+	if !errors.As(err, &lookAhead) {
+		t.Fatalf("expected a LookAheadError, got %v", err)
+	}
+	if !errors.As(guard.Err(), &lookAhead) {
+		t.Fatalf("the guard must record the violation, got %v", guard.Err())
+	}
+}
+
+func TestBreakoutPassesLookAheadGuard(t *testing.T) {
+	config := lookAheadConfig(t)
+	config.Strategy = nil
+	if _, err := RunBacktest(&api.FileProvider{Path: "../testdata/BINANCE_BTCUSDT_30.json"}, config); err != nil {
+		t.Fatal(err)
+	}
+}
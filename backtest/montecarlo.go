@@ -0,0 +1,239 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/frappaf/tradingBot/utils"
+)
+
+const (
+	defaultMonteCarloRuns = 1000
+	defaultRuinLevel      = 0.5
+)
+
+// How the Monte Carlo builds a sequence of trades from the trades of the backtest
+type Resampling string
+
+const (
+	Bootstrap Resampling = "bootstrap" //Draw the trades with replacement, so the final equity changes too
+	Shuffle   Resampling = "shuffle"   //Reorder the trades, the final equity is always the same, only the path changes
+)
+
+// Retrieve the resampling with the given name
+func ParseResampling(name string) (Resampling, error) {
+	switch Resampling(name) {
+	case Bootstrap, Shuffle:
+		return Resampling(name), nil
+	}
+	return "", fmt.Errorf("UNKNOWN RESAMPLING %q, USE bootstrap OR shuffle", name)
+}
+
+// The settings of a Monte Carlo analysis
+// Runs is the number of resampled sequences (1000 if 0) built with the Resampling (bootstrap if empty)
+// A run is ruined when its equity falls to RuinLevel times the initial balance (0.5 if 0)
+// Seed makes the runs reproducible
+type MonteCarlo struct {
+	Runs       int
+	Resampling Resampling
+	RuinLevel  float32
+	Seed       int64
+}
+
+// The 5th, 25th, 50th, 75th and 95th percentiles and the mean of a distribution
+type Distribution struct {
+	P5, P25, P50, P75, P95, Mean float32
+}
+
+// The percentiles of the equity of the runs after Trade trades
+type EquityBand struct {
+	Trade int
+	Distribution
+}
+
+// The outcome of a Monte Carlo analysis
+// Every trade of the backtest is turned into a return on the equity before it, the runs compound the resampled returns
+// TradesToRuin is measured in trades and only covers the ruined runs, RuinProbability is their fraction
+// Bands has the equity percentiles after every trade, the first band is the initial balance
+type MonteCarloResult struct {
+	Runs, Trades    int
+	Resampling      Resampling
+	RuinLevel       float32
+	FinalEquity     Distribution
+	MaxDrawdown     Distribution
+	TradesToRuin    Distribution
+	RuinProbability float32
+	Bands           []EquityBand
+}
+
+// Resample the trades of the result to estimate the distributions of the final equity, of the max drawdown
+// and of the time to ruin
+func RunMonteCarlo(result Result, settings MonteCarlo) (MonteCarloResult, error) {
+	if settings.Runs <= 0 {
+		settings.Runs = defaultMonteCarloRuns
+	}
+	if settings.Resampling == "" {
+		settings.Resampling = Bootstrap
+	}
+	if _, err := ParseResampling(string(settings.Resampling)); err != nil {
+		return MonteCarloResult{}, err
+	}
+	if settings.RuinLevel <= 0 {
+		settings.RuinLevel = defaultRuinLevel
+	}
+	if settings.RuinLevel >= 1 {
+		return MonteCarloResult{}, fmt.Errorf("THE RUIN LEVEL MUST BE BELOW 1, GOT %v", settings.RuinLevel)
+	}
+	if len(result.Trades) == 0 {
+		return MonteCarloResult{}, fmt.Errorf("THE BACKTEST HAS NO TRADES TO RESAMPLE")
+	}
+
+	//The return of every trade on the equity before it, the trades are sorted by exit
+	returns := make([]float64, 0, len(result.Trades))
+	balance := float64(result.InitialBalance)
+	for _, trade := range result.Trades {
+		if balance <= 0 {
+			break
+		}
+		returns = append(returns, float64(trade.NetPL)/balance)
+		balance += float64(trade.NetPL)
+	}
+
+	res := MonteCarloResult{
+		Runs:       settings.Runs,
+		Trades:     len(returns),
+		Resampling: settings.Resampling,
+		RuinLevel:  settings.RuinLevel,
+	}
+	initial := float64(result.InitialBalance)
+	ruin := initial * float64(settings.RuinLevel)
+	random := rand.New(rand.NewSource(settings.Seed))
+
+	finals := make([]float32, settings.Runs)
+	drawdowns := make([]float32, settings.Runs)
+	var ruined []float32
+	paths := make([][]float32, len(returns)+1) //By trade, then by run
+	for i := range paths {
+		paths[i] = make([]float32, settings.Runs)
+	}
+
+	sequence := make([]float64, len(returns))
+	for run := 0; run < settings.Runs; run++ {
+		if settings.Resampling == Shuffle {
+			copy(sequence, returns)
+			random.Shuffle(len(sequence), func(i, j int) { sequence[i], sequence[j] = sequence[j], sequence[i] })
+		} else {
+			for i := range sequence {
+				sequence[i] = returns[random.Intn(len(returns))]
+			}
+		}
+
+		equity, peak, drawdown := initial, initial, 0.0
+		ruinedAt := 0
+		paths[0][run] = float32(equity)
+		for i, r := range sequence {
+			equity = math.Max(equity*(1+r), 0)
+			peak = math.Max(peak, equity)
+			drawdown = math.Max(drawdown, 1-equity/peak)
+			if ruinedAt == 0 && equity <= ruin {
+				ruinedAt = i + 1
+			}
+			paths[i+1][run] = float32(equity)
+		}
+
+		finals[run] = float32(equity)
+		drawdowns[run] = float32(drawdown)
+		if ruinedAt > 0 {
+			ruined = append(ruined, float32(ruinedAt))
+		}
+	}
+
+	res.FinalEquity = newDistribution(finals)
+	res.MaxDrawdown = newDistribution(drawdowns)
+	res.TradesToRuin = newDistribution(ruined)
+	res.RuinProbability = float32(len(ruined)) / float32(settings.Runs)
+	res.Bands = make([]EquityBand, len(paths))
+	for i, path := range paths {
+		res.Bands[i] = EquityBand{Trade: i, Distribution: newDistribution(path)}
+	}
+	return res, nil
+}
+
+// Compute the percentiles of the values, sorting them in place
+func newDistribution(values []float32) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	var sum float64
+	for _, value := range values {
+		sum += float64(value)
+	}
+	percentile := func(p float64) float32 {
+		return values[int(math.Round(p*float64(len(values)-1)))]
+	}
+	return Distribution{
+		P5:   percentile(0.05),
+		P25:  percentile(0.25),
+		P50:  percentile(0.5),
+		P75:  percentile(0.75),
+		P95:  percentile(0.95),
+		Mean: float32(sum / float64(len(values))),
+	}
+}
+
+// Print the distributions of the analysis
+func (res MonteCarloResult) Print() {
+	row := func(name, format string, distribution Distribution, scale float32) string {
+		value := func(v float32) string { return fmt.Sprintf(format, v*scale) }
+		return fmt.Sprintf("\n%v\t5%%: %v\t25%%: %v\t50%%: %v\t75%%: %v\t95%%: %v", name,
+			value(distribution.P5), value(distribution.P25), value(distribution.P50), value(distribution.P75), value(distribution.P95))
+	}
+
+	body := fmt.Sprintf("%v runs (%v) of %v trades", res.Runs, res.Resampling, res.Trades)
+	body += row("Final equity:", "%.2f", res.FinalEquity, 1)
+	body += row("Max drawdown:", "%.2f%%", res.MaxDrawdown, 100)
+	body += fmt.Sprintf("\nRuin (equity at %.0f%% of the initial balance) probability: %.2f%%", res.RuinLevel*100, res.RuinProbability*100)
+	if res.RuinProbability > 0 {
+		body += row("Trades to ruin:", "%.0f", res.TradesToRuin, 1)
+	}
+	utils.PrintStatus("MONTE CARLO", body)
+}
+
+// Write the equity percentile bands as CSV with a header, one row for every trade
+func WriteMonteCarloCSV(w io.Writer, res MonteCarloResult) error {
+	float := func(value float32) string { return strconv.FormatFloat(float64(value), 'f', -1, 32) }
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"trade", "p5", "p25", "p50", "p75", "p95", "mean"}); err != nil {
+		return err
+	}
+	for _, band := range res.Bands {
+		record := []string{strconv.Itoa(band.Trade), float(band.P5), float(band.P25), float(band.P50), float(band.P75), float(band.P95), float(band.Mean)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Write the equity percentile bands to a CSV file
+func SaveMonteCarlo(path string, res MonteCarloResult) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteMonteCarloCSV(file, res); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,148 @@
+package backtest
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/frappaf/tradingBot/bot"
+)
+
+func tradesResult(netPL ...float32) Result {
+	result := Result{InitialBalance: 10000}
+	for _, pl := range netPL {
+		result.Trades = append(result.Trades, bot.Trade{NetPL: pl})
+	}
+	return result
+}
+
+func TestNewDistribution(t *testing.T) {
+	sequence := make([]float32, 21)
+	for i := range sequence {
+		sequence[len(sequence)-1-i] = float32(i + 1)
+	}
+
+	tests := []struct {
+		name     string
+		values   []float32
+		expected Distribution
+	}{
+		{"empty", nil, Distribution{}},
+		{"single", []float32{7}, Distribution{P5: 7, P25: 7, P50: 7, P75: 7, P95: 7, Mean: 7}},
+		{"unsorted 1 to 21", sequence, Distribution{P5: 2, P25: 6, P50: 11, P75: 16, P95: 20, Mean: 11}},
+		{"rounded ranks", []float32{4, 1, 3, 2}, Distribution{P5: 1, P25: 2, P50: 3, P75: 3, P95: 4, Mean: 2.5}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := newDistribution(test.values); got != test.expected {
+				t.Fatalf("expected %+v, got %+v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunMonteCarlo(t *testing.T) {
+	constant := func(value float32) Distribution {
+		return Distribution{P5: value, P25: value, P50: value, P75: value, P95: value, Mean: value}
+	}
+
+	tests := []struct {
+		name        string
+		result      Result
+		settings    MonteCarlo
+		finalEquity Distribution
+		drawdown    Distribution
+		ruin        float32
+		toRuin      Distribution
+	}{
+		//The returns are +10% and -500/11000, in any order the final equity is 10500 and the drawdown 1/22
+		{"shuffle keeps the final equity", tradesResult(1000, -500), MonteCarlo{Runs: 200, Resampling: Shuffle, Seed: 1},
+			constant(10500), constant(1.0 / 22), 0, Distribution{}},
+		{"bootstrap of equal returns", tradesResult(1000, 1100), MonteCarlo{Runs: 50, Seed: 1},
+			constant(12100), constant(0), 0, Distribution{}},
+		{"ruin at the first trade", tradesResult(-6000), MonteCarlo{Runs: 10, Seed: 1},
+			constant(4000), constant(0.6), 1, constant(1)},
+		{"ruin level", tradesResult(-6000), MonteCarlo{Runs: 10, RuinLevel: 0.3, Seed: 1},
+			constant(4000), constant(0.6), 0, Distribution{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := RunMonteCarlo(test.result, test.settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !almostEqualDistribution(res.FinalEquity, test.finalEquity) {
+				t.Fatalf("expected the final equity %+v, got %+v", test.finalEquity, res.FinalEquity)
+			}
+			if !almostEqualDistribution(res.MaxDrawdown, test.drawdown) {
+				t.Fatalf("expected the max drawdown %+v, got %+v", test.drawdown, res.MaxDrawdown)
+			}
+			if res.RuinProbability != test.ruin || res.TradesToRuin != test.toRuin {
+				t.Fatalf("expected the ruin %v after %+v, got %v after %+v", test.ruin, test.toRuin, res.RuinProbability, res.TradesToRuin)
+			}
+			if len(res.Bands) != len(test.result.Trades)+1 || res.Bands[0].Distribution != constant(10000) {
+				t.Fatalf("expected %v bands starting at the initial balance, got %+v", len(test.result.Trades)+1, res.Bands)
+			}
+		})
+	}
+}
+
+// Compare the distributions up to the float32 rounding errors
+func almostEqualDistribution(got, expected Distribution) bool {
+	close := func(a, b float32) bool { return math.Abs(float64(a-b)) < 1e-3*math.Max(1, math.Abs(float64(b))) }
+	return close(got.P5, expected.P5) && close(got.P25, expected.P25) && close(got.P50, expected.P50) &&
+		close(got.P75, expected.P75) && close(got.P95, expected.P95) && close(got.Mean, expected.Mean)
+}
+
+func TestRunMonteCarloIsSeeded(t *testing.T) {
+	result := tradesResult(500, -300, 800, -200, -400, 1200, 100, -700)
+
+	first, err := RunMonteCarlo(result, MonteCarlo{Runs: 500, Seed: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := RunMonteCarlo(result, MonteCarlo{Runs: 500, Seed: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("the runs with the same seed differ")
+	}
+
+	other, err := RunMonteCarlo(result, MonteCarlo{Runs: 500, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(first.FinalEquity, other.FinalEquity) {
+		t.Fatal("the runs with different seeds are the same")
+	}
+
+	for _, distribution := range []Distribution{first.FinalEquity, first.MaxDrawdown} {
+		if !(distribution.P5 <= distribution.P25 && distribution.P25 <= distribution.P50 &&
+			distribution.P50 <= distribution.P75 && distribution.P75 <= distribution.P95) {
+			t.Fatalf("the percentiles are not sorted: %+v", distribution)
+		}
+	}
+}
+
+func TestRunMonteCarloErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   Result
+		settings MonteCarlo
+	}{
+		{"no trades", tradesResult(), MonteCarlo{}},
+		{"ruin level", tradesResult(100), MonteCarlo{RuinLevel: 1}},
+		{"resampling", tradesResult(100), MonteCarlo{Resampling: "jackknife"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := RunMonteCarlo(test.result, test.settings); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
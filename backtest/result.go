@@ -0,0 +1,164 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/frappaf/tradingBot/bot"
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+const secondsPerYear = 365.25 * 24 * 60 * 60
+
+// The outcome of a backtest
+// Every partial close of a position that scales out counts as a trade
+// The returns are fractions (0.1 is 10%), Sharpe and Sortino are annualised from the returns of the candles
+// Exposure is the fraction of the candles with an opened position
+// The Candles and the Strategy of a single symbol backtest are kept to draw the report, portfolio results don't have them
+// The Benchmark compares the run with buy and hold and with random entries
+// MonteCarlo is the resampling of the trades (see RunMonteCarlo), empty unless it is set by the caller
+type Result struct {
+	Symbol                      string
+	Candles                     []data.Candle
+	Strategy                    strategy.Strategy
+	InitialBalance, FinalEquity float32
+	Trades                      []bot.Trade
+	Equity                      []EquityPoint
+
+	TotalReturn, CAGR, MaxDrawdown float32
+	Sharpe, Sortino                float32
+	WinRate, ProfitFactor          float32
+	AverageWin, AverageLoss        float32
+	Exposure                       float32
+	NumberOfTrades                 int
+
+	Benchmark  Benchmark
+	MonteCarlo MonteCarloResult
+}
+
+// Compute the metrics of the trades and of the equity curve recorded every period seconds
+func newResult(initialBalance float32, trades []bot.Trade, equity []EquityPoint, period int64) Result {
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].ExitTimestamp < trades[j].ExitTimestamp })
+
+	result := Result{
+		InitialBalance: initialBalance,
+		FinalEquity:    initialBalance,
+		Trades:         trades,
+		Equity:         equity,
+		NumberOfTrades: len(trades),
+	}
+	if len(equity) > 0 {
+		result.FinalEquity = equity[len(equity)-1].Equity
+	}
+	result.TotalReturn = result.FinalEquity/initialBalance - 1
+
+	result.tradeMetrics()
+	result.equityMetrics(period)
+
+	return result
+}
+
+// Win rate, profit factor and average win/loss from the net P/L of the trades
+func (result *Result) tradeMetrics() {
+	var wins, losses int
+	var profits, lossesSum float32
+	for _, trade := range result.Trades {
+		if trade.NetPL > 0 {
+			wins++
+			profits += trade.NetPL
+		} else {
+			losses++
+			lossesSum -= trade.NetPL
+		}
+	}
+
+	if len(result.Trades) > 0 {
+		result.WinRate = float32(wins) / float32(len(result.Trades))
+	}
+	if wins > 0 {
+		result.AverageWin = profits / float32(wins)
+	}
+	if losses > 0 {
+		result.AverageLoss = -lossesSum / float32(losses)
+	}
+	switch {
+	case lossesSum > 0:
+		result.ProfitFactor = profits / lossesSum
+	case profits > 0:
+		result.ProfitFactor = float32(math.Inf(1))
+	}
+}
+
+// CAGR, max drawdown, Sharpe, Sortino and exposure from the equity curve
+func (result *Result) equityMetrics(period int64) {
+	if len(result.Equity) < 2 {
+		return
+	}
+
+	first, last := result.Equity[0], result.Equity[len(result.Equity)-1]
+	years := float64(last.Timestamp-first.Timestamp) / secondsPerYear
+	if years > 0 && result.FinalEquity > 0 {
+		result.CAGR = float32(math.Pow(float64(result.FinalEquity/result.InitialBalance), 1/years) - 1)
+	}
+
+	exposed := 0
+	returns := make([]float64, 0, len(result.Equity))
+	previous := result.InitialBalance
+	for _, point := range result.Equity {
+		if point.Drawdown > result.MaxDrawdown {
+			result.MaxDrawdown = point.Drawdown
+		}
+		if point.Exposed {
+			exposed++
+		}
+		if previous > 0 {
+			returns = append(returns, float64(point.Equity/previous-1))
+		}
+		previous = point.Equity
+	}
+	result.Exposure = float32(exposed) / float32(len(result.Equity))
+
+	//Annualise the ratios of the candle returns
+	var mean, variance, downside float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	variance /= float64(len(returns))
+	downside /= float64(len(returns))
+
+	scale := math.Sqrt(secondsPerYear / float64(period))
+	if variance > 0 {
+		result.Sharpe = float32(mean / math.Sqrt(variance) * scale)
+	}
+	if downside > 0 {
+		result.Sortino = float32(mean / math.Sqrt(downside) * scale)
+	}
+}
+
+// Print a summary of the metrics
+func (result Result) Print() {
+	body := fmt.Sprintf("Initial balance: %f\tFinal equity: %f", result.InitialBalance, result.FinalEquity)
+	body += fmt.Sprintf("\nTotal return: %.2f%%\tCAGR: %.2f%%\tMax drawdown: %.2f%%", result.TotalReturn*100, result.CAGR*100, result.MaxDrawdown*100)
+	body += fmt.Sprintf("\nSharpe: %.2f\tSortino: %.2f", result.Sharpe, result.Sortino)
+	body += fmt.Sprintf("\nTrades: %v\tWin rate: %.2f%%\tProfit factor: %.2f", result.NumberOfTrades, result.WinRate*100, result.ProfitFactor)
+	body += fmt.Sprintf("\nAverage win: %f\tAverage loss: %f", result.AverageWin, result.AverageLoss)
+	body += fmt.Sprintf("\nExposure time: %.2f%%", result.Exposure*100)
+
+	if benchmark := result.Benchmark; benchmark.RandomRuns > 0 {
+		body += fmt.Sprintf("\n\nBuy and hold return: %.2f%%\tExcess return: %.2f%%", benchmark.BuyAndHoldReturn*100, benchmark.ExcessReturn*100)
+		body += fmt.Sprintf("\nRandom entries (%v runs) mean return: %.2f%%\tExcess return: %.2f%%\tPercentile: %.1f",
+			benchmark.RandomRuns, benchmark.RandomMeanReturn*100, benchmark.ExcessOverRandom*100, benchmark.Percentile*100)
+	}
+
+	utils.PrintStatus("BACKTEST RESULT", body)
+}
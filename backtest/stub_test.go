@@ -0,0 +1,73 @@
+package backtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/api/stub"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+func date(t *testing.T, value string) int64 {
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Unix()
+}
+
+// The fixture served by the stub through the finnhub provider must give the same backtest as the file itself
+func TestRunBacktestThroughStub(t *testing.T) {
+	server := stub.NewServer("../testdata")
+	defer server.Close()
+
+	config := Config{
+		Symbol:        "BINANCE:BTCUSDT",
+		From:          date(t, "2021-11-01"),
+		To:            date(t, "2022-01-01"),
+		End:           date(t, "2022-02-01"),
+		Resolution:    "30",
+		BenchmarkRuns: -1,
+		Logger:        utils.Discard,
+	}
+
+	finnhub := &api.FinnhubProvider{BaseURL: server.URL + "/api/v1", RequestsPerMinute: 60000}
+	res, err := RunBacktest(finnhub, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := RunBacktest(&api.FileProvider{Path: "../testdata/BINANCE_BTCUSDT_30.json"}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Candles) == 0 || res.NumberOfTrades == 0 {
+		t.Fatalf("the backtest replayed %v candles and %v trades, expected some of both", len(res.Candles), res.NumberOfTrades)
+	}
+	if len(res.Candles) != len(expected.Candles) {
+		t.Errorf("replayed %v candles through the stub, %v from the file", len(res.Candles), len(expected.Candles))
+	}
+	if res.NumberOfTrades != expected.NumberOfTrades || res.FinalEquity != expected.FinalEquity {
+		t.Errorf("got %v trades and a final equity of %v through the stub, %v and %v from the file",
+			res.NumberOfTrades, res.FinalEquity, expected.NumberOfTrades, expected.FinalEquity)
+	}
+}
+
+// GetResponse must reach the stub through its base URL
+func TestGetResponseThroughStub(t *testing.T) {
+	server := stub.NewServer("../testdata")
+	defer server.Close()
+
+	from, to := date(t, "2022-01-01"), date(t, "2022-01-08")-1
+	candles, err := api.GetResponse(server.URL+"/api/v1", "BINANCE:BTCUSDT", "D", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 7 {
+		t.Fatalf("expected 7 daily candles, got %v", len(candles))
+	}
+	if candles[0].Timestamp != from {
+		t.Fatalf("expected the first candle at %v, got %v", from, candles[0].Timestamp)
+	}
+}
@@ -0,0 +1,137 @@
+package backtest
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// A combination of the sweep, Rank 1 is the best Score
+type SweepResult struct {
+	Rank   int
+	Params strategy.Params
+	Score  float32
+	Result Result
+}
+
+// Run a backtest of the base config for every parameters of the grid and retrieve the results in the order of the grid
+// The backtests run concurrently on a pool of workers (the number of CPUs if 0) and log nothing,
+// the provider must be safe for concurrent use (e.g. a Dataset)
+// The candles and the strategy of the results are dropped to save memory
+func runGrid(provider api.CandleProvider, base Config, grid []strategy.Params, workers int) ([]Result, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	base.Logger = utils.Discard
+	results := make([]Result, len(grid))
+	errs := make([]error, len(grid))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job], errs[job] = runParams(provider, base, grid[job])
+			}
+		}()
+	}
+
+	for job := range grid {
+		jobs <- job
+	}
+	close(jobs)
+	wg.Wait()
+
+	for job, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("backtest with %v: %w", grid[job], err)
+		}
+	}
+	return results, nil
+}
+
+// Run a backtest with the params dropping the candles and the strategy of the result
+func runParams(provider api.CandleProvider, config Config, params strategy.Params) (Result, error) {
+	config.Params = params
+	result, err := RunBacktest(provider, config)
+	result.Candles, result.Strategy = nil, nil
+	return result, err
+}
+
+// Run a backtest of the base config for every parameters of the grid (see runGrid)
+// and rank them by the objective (sharpe if nil), best first
+func RunSweep(provider api.CandleProvider, base Config, grid []strategy.Params, workers int, objective Objective) ([]SweepResult, error) {
+	if objective == nil {
+		objective = Objectives["sharpe"]
+	}
+
+	results, err := runGrid(provider, base, grid, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	sweep := make([]SweepResult, len(results))
+	for i, result := range results {
+		sweep[i] = SweepResult{Params: grid[i], Score: objective(result), Result: result}
+	}
+	sort.SliceStable(sweep, func(i, j int) bool { return sweep[i].Score > sweep[j].Score })
+	for i := range sweep {
+		sweep[i].Rank = i + 1
+	}
+	return sweep, nil
+}
+
+// Write the ranked combinations as CSV with a header: the parameters, the score and the metrics
+func WriteSweepCSV(w io.Writer, sweep []SweepResult) error {
+	float := func(value float32) string { return strconv.FormatFloat(float64(value), 'f', -1, 32) }
+
+	header := append([]string{"rank"}, strategy.ParamNames...)
+	header = append(header, "score", "total_return", "cagr", "max_drawdown", "sharpe", "sortino",
+		"win_rate", "profit_factor", "average_win", "average_loss", "exposure", "trades")
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, combination := range sweep {
+		record := []string{strconv.Itoa(combination.Rank)}
+		for _, name := range strategy.ParamNames {
+			value, _ := combination.Params.Value(name)
+			record = append(record, float(value))
+		}
+		result := combination.Result
+		record = append(record, float(combination.Score), float(result.TotalReturn), float(result.CAGR), float(result.MaxDrawdown),
+			float(result.Sharpe), float(result.Sortino), float(result.WinRate), float(result.ProfitFactor),
+			float(result.AverageWin), float(result.AverageLoss), float(result.Exposure), strconv.Itoa(result.NumberOfTrades))
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Write the ranked combinations to a CSV file
+func SaveSweep(path string, sweep []SweepResult) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteSweepCSV(file, sweep); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,169 @@
+package backtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/bot"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// The metric maximised by the optimisations
+type Objective func(Result) float32
+
+// The objectives by name
+var Objectives = map[string]Objective{
+	"sharpe":        func(result Result) float32 { return result.Sharpe },
+	"sortino":       func(result Result) float32 { return result.Sortino },
+	"return":        func(result Result) float32 { return result.TotalReturn },
+	"profit-factor": func(result Result) float32 { return result.ProfitFactor },
+}
+
+// Retrieve the objective with the given name
+func ParseObjective(name string) (Objective, error) {
+	if objective, ok := Objectives[name]; ok {
+		return objective, nil
+	}
+
+	names := make([]string, 0, len(Objectives))
+	for name := range Objectives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("UNKNOWN OBJECTIVE %q, USE %v", name, strings.Join(names, ", "))
+}
+
+// The settings of a walk-forward optimisation
+// The Config is the base of every backtest: its From is the start of the daily history, its To the start of the first
+// in-sample window and its End the end of the last out-of-sample window (now if 0), its Params are ignored
+// Every in-sample window lasts InSample seconds and is followed by an out-of-sample window of OutOfSample seconds,
+// then the windows roll forward by OutOfSample, until End or until an in-sample window has no candles
+// The Grid contains the candidate parameters, the best one by the Objective (sharpe if nil) in-sample is used out-of-sample
+// The in-sample backtests run on a pool of Workers (see runGrid)
+type WalkForward struct {
+	Config                Config
+	InSample, OutOfSample int64
+	Grid                  []strategy.Params
+	Objective             Objective
+	Workers               int
+}
+
+// A step of the walk-forward
+type WalkForwardWindow struct {
+	InSampleFrom, OutOfSampleFrom, OutOfSampleTo int64
+	Params                                       strategy.Params
+	InSampleScore                                float32
+	OutOfSample                                  Result
+}
+
+// The outcome of a walk-forward
+// OutOfSample joins the out-of-sample windows: every window starts with the final equity of the previous one
+// (a position still opened at the end of a window is closed at the last close, see Config.CloseAtEnd)
+type WalkForwardResult struct {
+	Windows     []WalkForwardWindow
+	OutOfSample Result
+}
+
+// Run the walk-forward optimisation
+// The backtests log nothing
+// The provider must be safe for concurrent use (e.g. a Dataset)
+func RunWalkForward(provider api.CandleProvider, walkForward WalkForward) (WalkForwardResult, error) {
+	if walkForward.InSample <= 0 || walkForward.OutOfSample <= 0 {
+		return WalkForwardResult{}, fmt.Errorf("THE WINDOWS MUST BE POSITIVE")
+	}
+	if len(walkForward.Grid) == 0 {
+		return WalkForwardResult{}, fmt.Errorf("THE GRID MUST CONTAIN AT LEAST ONE COMBINATION")
+	}
+	objective := walkForward.Objective
+	if objective == nil {
+		objective = Objectives["sharpe"]
+	}
+	period, err := api.ResolutionSeconds(walkForward.Config.Resolution)
+	if err != nil {
+		return WalkForwardResult{}, err
+	}
+
+	base := walkForward.Config
+	base.Logger = utils.Discard
+	initialBalance, end := base.bounds()
+	base.BenchmarkRuns = -1
+
+	var res WalkForwardResult
+	var trades []bot.Trade
+	var equity []EquityPoint
+	balance := initialBalance
+
+	for start := base.To; start+walkForward.InSample < end; start += walkForward.OutOfSample {
+		window := WalkForwardWindow{
+			InSampleFrom:    start,
+			OutOfSampleFrom: start + walkForward.InSample,
+			OutOfSampleTo:   start + walkForward.InSample + walkForward.OutOfSample,
+		}
+		if window.OutOfSampleTo > end {
+			window.OutOfSampleTo = end
+		}
+
+		//In-sample: the best parameters of the grid
+		inSample := base
+		inSample.To, inSample.End = window.InSampleFrom, window.OutOfSampleFrom
+		results, err := runGrid(provider, inSample, walkForward.Grid, walkForward.Workers)
+		if err != nil {
+			return res, err
+		}
+		if len(results[0].Equity) == 0 {
+			break
+		}
+		for i, result := range results {
+			if score := objective(result); i == 0 || score > window.InSampleScore {
+				window.Params, window.InSampleScore = walkForward.Grid[i], score
+			}
+		}
+
+		//Out-of-sample: the best parameters on the following window
+		outOfSample := base
+		outOfSample.To, outOfSample.End = window.OutOfSampleFrom, window.OutOfSampleTo
+		outOfSample.InitialBalance = balance
+		outOfSample.CloseAtEnd = true
+		if window.OutOfSample, err = runParams(provider, outOfSample, window.Params); err != nil {
+			return res, err
+		}
+
+		trades = append(trades, window.OutOfSample.Trades...)
+		equity = append(equity, window.OutOfSample.Equity...)
+		balance = window.OutOfSample.FinalEquity
+		res.Windows = append(res.Windows, window)
+	}
+	if len(res.Windows) == 0 {
+		return res, fmt.Errorf("THE PERIOD IS SHORTER THAN THE IN-SAMPLE WINDOW")
+	}
+
+	restateDrawdown(equity, initialBalance)
+	res.OutOfSample = newResult(initialBalance, trades, equity, period)
+	res.OutOfSample.Symbol = base.Symbol
+	return res, nil
+}
+
+// Print the parameters and the out-of-sample return of every window, then the metrics of the joined out-of-sample run
+func (res WalkForwardResult) Print() {
+	body := ""
+	for i, window := range res.Windows {
+		if i > 0 {
+			body += "\n"
+		}
+		body += fmt.Sprintf("Window %v: in-sample from %v, out-of-sample %v - %v\n", i+1,
+			formatDate(window.InSampleFrom), formatDate(window.OutOfSampleFrom), formatDate(window.OutOfSampleTo))
+		body += fmt.Sprintf("Params: %v\tIn-sample score: %.2f\tOut-of-sample return: %.2f%%",
+			window.Params, window.InSampleScore, window.OutOfSample.TotalReturn*100)
+	}
+	utils.PrintStatus("WALK FORWARD", body)
+
+	res.OutOfSample.Print()
+}
+
+func formatDate(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+}
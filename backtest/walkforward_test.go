@@ -0,0 +1,135 @@
+package backtest
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// A strategy that opens a long position on every candle without a position, with levels the price never reaches
+type holdingStrategy struct{}
+
+func (holdingStrategy) Initialize(history []data.Candle) {}
+
+func (holdingStrategy) OnCandle(ctx context.Context, candle data.Candle, position int8) []strategy.Signal {
+	if position != strategy.Neutral {
+		return nil
+	}
+	return []strategy.Signal{{Action: strategy.Open, Side: strategy.Long, Price: candle.Close, StopLoss: candle.Close / 100, TakeProfit: candle.Close * 100}}
+}
+
+func holdingConfig(t *testing.T) Config {
+	return Config{
+		Symbol:        "BINANCE:BTCUSDT",
+		From:          date(t, "2021-11-01"),
+		To:            date(t, "2021-12-01"),
+		End:           date(t, "2022-01-31"),
+		Resolution:    "30",
+		Costs:         broker.Costs{TakerFee: 0.001, FixedSlippage: 0.0005},
+		BenchmarkRuns: -1,
+		Logger:        utils.Discard,
+		Strategy:      func(string) strategy.Strategy { return holdingStrategy{} },
+	}
+}
+
+func TestCloseAtEnd(t *testing.T) {
+	provider := &api.FileProvider{Path: "../testdata/{symbol}_30.json"}
+	allocations := map[string]float32{"BINANCE:BTCUSDT": 0.5, "BINANCE:ETHUSDT": 0.5}
+
+	tests := []struct {
+		name       string
+		closeAtEnd bool
+		portfolio  bool
+		trades     int
+	}{
+		{"single symbol held", false, false, 0},
+		{"single symbol closed", true, false, 1},
+		{"portfolio held", false, true, 0},
+		{"portfolio closed", true, true, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := holdingConfig(t)
+			config.CloseAtEnd = test.closeAtEnd
+
+			var result Result
+			var err error
+			if test.portfolio {
+				result, err = RunPortfolioBacktest(provider, allocations, config)
+			} else {
+				result, err = RunBacktest(provider, config)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Trades) != test.trades {
+				t.Fatalf("expected %v trades, got %+v", test.trades, result.Trades)
+			}
+			last := result.Equity[len(result.Equity)-1]
+			if last.Exposed != !test.closeAtEnd {
+				t.Fatalf("expected the last point exposed %v, got %+v", !test.closeAtEnd, last)
+			}
+			if !test.closeAtEnd {
+				return
+			}
+
+			var netPL float32
+			for _, trade := range result.Trades {
+				if trade.ExitReason != "END" || trade.ExitTimestamp != last.Timestamp || trade.ExitFee <= 0 {
+					t.Fatalf("expected a trade closed with its fee at the end %v, got %+v", last.Timestamp, trade)
+				}
+				netPL += trade.NetPL
+			}
+			if !almostEqual(result.FinalEquity, 10000+netPL) || !almostEqual(last.Equity, last.Balance) {
+				t.Fatalf("expected the final equity %v, got %v (%+v)", 10000+netPL, result.FinalEquity, last)
+			}
+		})
+	}
+}
+
+func TestWalkForwardClosesTheWindows(t *testing.T) {
+	provider := &api.FileProvider{Path: "../testdata/{symbol}_30.json"}
+	walkForward := WalkForward{
+		Config:      holdingConfig(t),
+		InSample:    10 * day,
+		OutOfSample: 10 * day,
+		Grid:        []strategy.Params{strategy.DefaultParams()},
+		Workers:     1,
+	}
+
+	res, err := RunWalkForward(provider, walkForward)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Windows) < 2 {
+		t.Fatalf("expected at least 2 windows, got %v", len(res.Windows))
+	}
+
+	//Every window closes the position it opened, so the trades explain the whole change of the equity
+	var netPL float32
+	for i, window := range res.Windows {
+		if len(window.OutOfSample.Trades) != 1 || window.OutOfSample.Trades[0].ExitReason != "END" {
+			t.Fatalf("expected the window %v to close its position at the end, got %+v", i+1, window.OutOfSample.Trades)
+		}
+		netPL += window.OutOfSample.Trades[0].NetPL
+	}
+	if len(res.OutOfSample.Trades) != len(res.Windows) {
+		t.Fatalf("expected %v trades, got %v", len(res.Windows), len(res.OutOfSample.Trades))
+	}
+	if !almostEqual(res.OutOfSample.FinalEquity, 10000+netPL) {
+		t.Fatalf("expected the final equity %v, got %v", 10000+netPL, res.OutOfSample.FinalEquity)
+	}
+}
+
+// Compare the amounts up to the float32 rounding errors
+func almostEqual(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-2
+}
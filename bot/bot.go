@@ -3,287 +3,417 @@ package bot
 import (
 	"context"
 	"fmt"
-	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ably/ably-go/ably"
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/broker"
 	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/sizing"
+	"github.com/frappaf/tradingBot/strategy"
 	"github.com/frappaf/tradingBot/utils"
 )
 
-const (
-	minDifference float32 = 300.0
-	long          int8    = 1
-	short                 = -1
-	neutral               = 0
-)
+const day int64 = 24 * 60 * 60
+
+const defaultAblyKey = "TsoT_A.ll-gaA:PPOPgVew_cMvzi_SrVd_QbuQvm_u_puG1IYMQVjR0S0"
 
 // The bot is the core of the engine
-// It contains a collection, the current balance,
-// The current area that contains the price
-// The current position that could be [long, short, neutral]
+// It executes the signals of the strategy and manages the current position
+// The broker executes the orders and holds the balance, the current position could be [long, short, neutral]
 // The stopLoss, takeProfit are sensitive values, when the price reaches one of them the current position is closed
 // The buyPrice stands for the price when it opend a position
 // The units is the number of units long or short
+// The Strategy decides when to open a position, if nil the breakout strategy is used
+// The Provider is the source of the daily history, if nil the finnhub provider is used
+// The Symbol is the traded instrument (e.g. BINANCE:BTCUSDT)
+// The Channel is the ably channel streaming the live price, if empty it is derived from the symbol
+// The AblyKey authenticates the stream, if empty the public coindesk key is used
+// The Allocation is the fraction of the broker balance the bot can use, it is 1 unless the broker is shared
+// The Trades are the records of the closed positions, if the Journal is set they are also written to its files
+// The BothHit rule decides the exit when a candle touches both the stopLoss and the takeProfit
+// The Sizer decides the units of a new position, if nil the whole capital is invested
+// The MaxExposure caps the notional of a position as a multiple of the capital, if 0 it is 1 (no leverage)
+// The Trailing stop and the BreakEven profit (a fraction of the entry price, 0 to disable) move the stopLoss of the opened position
+// The Ladder contains the fractions of the position closed at the successive take profits of the signal, if empty the position is closed at once
+// The Logger receives the logs of the bot and of its strategy, if nil they go to the standard output
 type Bot struct {
-	Collection                    data.Collection
-	CurrentMoney                  float32
-	currentPosition               Position
-	currentArea, currentDayCandle data.Candle
+	Strategy        strategy.Strategy
+	Provider        api.CandleProvider
+	Broker          broker.Broker
+	Symbol, Channel string
+	AblyKey         string
+	Allocation      float32
+	BothHit         BothHitRule
+	Sizer           sizing.Sizer
+	MaxExposure     float32
+	Trailing        TrailingStop
+	BreakEven       float32
+	Ladder          []float32
+	Trades          []Trade
+	Journal         *Journal
+	Logger          *utils.Logger
+	currentPosition Position
+	recentCandles   []data.Candle
+	stepBalance     float32 //The balance at the start of the step of a portfolio replay, used while inStep
+	inStep          bool
 }
 
-// Initialize all the values
-// It calls the FetchData and FindInterestingAreasAndKeyLevels methods of the collection
-func (bot *Bot) Initialize(initialAmount float32, from, to int64) error {
+// Initialize all the values, if the Broker is nil a paper broker with the initial amount is used
+// It fetches the daily history from the provider and initializes the strategy with the days closed at to
+func (bot *Bot) Initialize(symbol string, initialAmount float32, from, to int64) error {
 	if initialAmount <= 0 {
 		return fmt.Errorf("INITIAL AMOUNT MUST BE POSITIVE")
 	}
 
-	bot.CurrentMoney = initialAmount
-	bot.currentArea = data.Candle{}
+	executor := bot.Broker
+	if executor == nil {
+		executor = broker.NewPaper(initialAmount)
+	}
+	return bot.initialize(symbol, executor, 1, from, to)
+}
+
+// Initialize the bot using the given broker and allocation
+func (bot *Bot) initialize(symbol string, executor broker.Broker, allocation float32, from, to int64) error {
+	if symbol == "" {
+		return fmt.Errorf("SYMBOL MUST NOT BE EMPTY")
+	}
+
+	bot.Symbol = symbol
+	bot.Broker = executor
+	bot.Allocation = allocation
 	bot.currentPosition = Position{}
+	bot.Trades = nil
+	bot.recentCandles = nil
 
-	bot.currentDayCandle = data.Candle{
-		Timestamp: 0,
+	if bot.Strategy == nil {
+		bot.Strategy = &strategy.Breakout{}
+	}
+	bot.SetLogger(bot.Logger)
+	if bot.Provider == nil {
+		bot.Provider = api.NewFinnhubProvider()
 	}
 
-	err := bot.Collection.FetchData(from, to)
+	daily, err := bot.Provider.GetCandles(bot.Symbol, "D", from, to)
 	if err != nil {
 		return err
 	}
 
-	bot.Collection.FindInterestingAreasAndKeyLevels()
+	//The day containing to is still forming, the strategy must not see it
+	bot.Strategy.Initialize(data.ClosedBefore(daily, day, to))
 
 	return nil
 }
 
-// Find and returns, if exists, the area that contains the given candle
-// If it not exists returns an empty candle and an error
-func (bot *Bot) findArea(can data.Candle) (data.Candle, error) {
+// Strategies can log with the logger of the bot, like the Breakout does
+type loggingStrategy interface{ SetLogger(logger *utils.Logger) }
 
-	index := binarySearchForCandles(bot.Collection.InterestAreas, can, 0, len(bot.Collection.InterestAreas))
-	if index == -1 {
-		return data.Candle{}, fmt.Errorf("AREA NOT FOUND")
+// Set the logger of the bot and of its strategy
+func (bot *Bot) SetLogger(logger *utils.Logger) {
+	bot.Logger = logger
+	if logging, ok := bot.Strategy.(loggingStrategy); ok {
+		logging.SetLogger(bot.log())
 	}
+}
 
-	return bot.Collection.InterestAreas[index], nil
+// Retrieve the logger of the bot
+func (bot *Bot) log() *utils.Logger {
+	if bot.Logger == nil {
+		return utils.Default
+	}
+	return bot.Logger
 }
 
-// Recursive binary search for candles
-func binarySearchForCandles(arr []data.Candle, can data.Candle, from, to int) int {
-	index := (to-from)/2 + from
+// Close the current position placing an opposite market order at the given value
+func (bot *Bot) closePosition(value float32, timestamp int64, reason string) {
+	bot.reducePosition(bot.currentPosition.Units, value, timestamp, reason)
+}
 
-	if from > to {
-		return -1
+// Close the opened position placing an opposite market order at the given value, e.g. at the end of a backtest
+// The trade is recorded with the END reason, without a position it does nothing
+func (bot *Bot) Liquidate(value float32, timestamp int64) {
+	if bot.currentPosition.Position != strategy.Neutral {
+		bot.closePosition(value, timestamp, endReason)
 	}
+}
 
-	if arr[index].Contains(can.High) {
-		return index
-	}
+// Close the next tranche of the position at its take profit
+// The TakeProfit of the position moves to the following tranche
+// It retrieves false if the tranche is still opened because the order failed
+func (bot *Bot) closeTranche(value float32, timestamp int64) bool {
+	next := bot.currentPosition.nextTranche()
+	tranche := &bot.currentPosition.Tranches[next]
 
-	if arr[index].High < can.Close {
+	trade, ok := bot.reducePosition(tranche.Units, value, timestamp, takeProfitReason)
+	if !ok {
+		return false
+	}
 
-		if index == len(arr)-1 {
-			return -1
-		}
-		return binarySearchForCandles(arr, can, index+1, to)
-	} else {
-		if index == 0 {
-			return -1
-		}
-		return binarySearchForCandles(arr, can, from, index-1)
+	tranche.Closed = true
+	tranche.ExitPrice = trade.ExitPrice
+	tranche.PL = trade.NetPL
+	if following := bot.currentPosition.nextTranche(); following != -1 {
+		bot.currentPosition.TakeProfit = bot.currentPosition.Tranches[following].TakeProfit
 	}
+	return true
 }
 
-// Close the current position
-// It set all the data to 0
-// Calculate the Profit/Loss and add to the current balance
-func (bot *Bot) closePosition(value float32) {
+// Close the given units of the current position placing an opposite market order at the given value
+// The take profits are orders resting at their level, so they pay the maker fee without slippage
+// It records the trade and, if no units are left, set all the data to 0
+// The broker adds the Profit/Loss to the balance and subtracts the costs
+// It retrieves false if the order failed or closed no units, the position is unchanged then
+func (bot *Bot) reducePosition(units, value float32, timestamp int64, reason string) (Trade, bool) {
+
+	fill, err := bot.Broker.PlaceOrder(broker.Order{
+		Symbol:    bot.Symbol,
+		Side:      -bot.currentPosition.Position,
+		Type:      broker.Market,
+		Resting:   reason == takeProfitReason,
+		Units:     units,
+		Price:     value,
+		Timestamp: timestamp,
+	})
+	if err != nil {
+		bot.log().Println("Cannot close the position:", err)
+		return Trade{}, false
+	}
+	if fill.Units <= 0 {
+		bot.log().Println("Cannot close the position: the order closed no units")
+		return Trade{}, false
+	}
+
+	trade := newTrade(bot.Symbol, bot.currentPosition, fill, reason)
+	bot.Trades = append(bot.Trades, trade)
+	if bot.Journal != nil {
+		if err := bot.Journal.Record(trade); err != nil {
+			bot.log().Println("Cannot write the journal:", err)
+		}
+	}
 
-	p_l := float32(bot.currentPosition.Position) * (value - bot.currentPosition.BuyPrice) * bot.currentPosition.Units
-	bot.CurrentMoney += p_l
+	if log := bot.log(); log.Enabled() {
+		title := "POSITION CLOSED"
+		if fill.Units < bot.currentPosition.Units {
+			title = "POSITION REDUCED"
+		}
+		log.PrintStatus(title, "Closing "+fmt.Sprintf("%f", fill.Units)+" units ("+reason+") with P/L: "+fmt.Sprintf("%f", trade.NetPL)+
+			"\nGross P/L: "+fmt.Sprintf("%f", trade.GrossPL)+"\tFees: "+fmt.Sprintf("%f", trade.EntryFee+trade.ExitFee)+
+			"\tSlippage: "+fmt.Sprintf("%f", trade.Slippage)+"\tFunding: "+fmt.Sprintf("%f", trade.Funding))
+	}
 
-	utils.PrintStatus("POSITION CLOSED", "Closing position with P/L: "+fmt.Sprintf("%f", p_l))
-	bot.currentPosition.BuyPrice = 0
-	bot.currentPosition.TakeProfit = 0
-	bot.currentPosition.StopLoss = 0
-	bot.currentPosition.Units = 0
-	bot.currentPosition.Position = neutral
+	bot.currentPosition.Units -= fill.Units
+	bot.currentPosition.RealisedPL += trade.NetPL
+	if bot.currentPosition.Units <= 0 {
+		bot.currentPosition = Position{Position: strategy.Neutral}
+	}
 
+	return trade, true
 }
 
 // Print the global status of the bot
 func (bot *Bot) Print() {
 
-	body := "Current balance: " + fmt.Sprintf("%f", bot.CurrentMoney)
+	body := "Symbol: " + bot.Symbol
+	body += "\nCurrent balance: " + fmt.Sprintf("%f", bot.Broker.Balance())
+	if bot.Allocation < 1 {
+		body += "\tAllocation: " + fmt.Sprintf("%.2f%%", bot.Allocation*100)
+	}
 	switch bot.currentPosition.Position {
-	case neutral:
+	case strategy.Neutral:
 		body += "\nCurrent position: NEUTRAL"
-	case long:
+	case strategy.Long:
 		body += "\nCurrent position: LONG\tStopLoss: " + fmt.Sprintf("%f", bot.currentPosition.StopLoss) + "\tTakeProfit: " + fmt.Sprintf("%f", bot.currentPosition.TakeProfit)
-	case short:
+	case strategy.Short:
 		body += "\nCurrent position: SHORT\tStopLoss: " + fmt.Sprintf("%f", bot.currentPosition.StopLoss) + "\tTakeProfit: " + fmt.Sprintf("%f", bot.currentPosition.TakeProfit)
 	}
+	for i, tranche := range bot.currentPosition.Tranches {
+		body += fmt.Sprintf("\nTranche %v: %f units at %f", i+1, tranche.Units, tranche.TakeProfit)
+		if tranche.Closed {
+			body += fmt.Sprintf("\tCLOSED at %f with P/L: %f", tranche.ExitPrice, tranche.PL)
+		}
+	}
 
-	body += "\n\nCurrentArea:\n"
-	body += bot.currentArea.ToString()
-
-	body += "\n\nCurrent daily candle:\n"
-	body += bot.currentDayCandle.ToString()
+	//Strategies can describe their state
+	if status, ok := bot.Strategy.(interface{ ToString() string }); ok {
+		body += "\n\n" + status.ToString()
+	}
 
-	utils.PrintStatus("BOT STATUS", body)
+	bot.log().PrintStatus("BOT STATUS", body)
 }
 
-// Given a new candle it decides whether to open a position or not
-func (bot *Bot) Predict(candle data.Candle, present time.Time) {
-
-	bot.updateCurrentDailyCandle(candle.Close, present)
+// Given a new candle it closes the position if the stopLoss or the takeProfit is reached within the candle
+// or moves the stopLoss if the position is still opened
+// Then it passes the candle to the strategy and executes the signals
+func (bot *Bot) Predict(ctx context.Context, candle data.Candle) {
 
-	bot.Print()
-	utils.PrintStatus("CURRENT PRICE", candle.ToString())
-
-	//Check if the price has reached the stopLoss or the takeProfit
-	if bot.currentPosition.Position == long && (bot.currentPosition.StopLoss >= candle.Close || bot.currentPosition.TakeProfit <= candle.Close) {
-		bot.closePosition(candle.Close)
-	}
-	if bot.currentPosition.Position == short && (bot.currentPosition.StopLoss <= candle.Close || bot.currentPosition.TakeProfit >= candle.Close) {
-		bot.closePosition(candle.Close)
+	//The status is built only if it is written, the quiet bots skip it
+	log := bot.log()
+	if log.Enabled() {
+		bot.Print()
+		log.PrintStatus("CURRENT PRICE", candle.ToString())
 	}
 
-	//If the price in not in an interesting area yet search again
-	if bot.currentArea.Close == 0.0 {
-		closest, err := bot.findArea(candle)
-		if err == nil {
-			bot.currentArea = closest
-			fmt.Println("Price inside interesting area")
-			closest.Print()
-		} else {
-			fmt.Println("Area not yet discovered...")
+	//Simulated brokers fill the pending orders with the candle
+	if simulator, ok := bot.Broker.(broker.Simulator); ok {
+		for _, fill := range simulator.OnCandle(bot.Symbol, candle) {
+			log.PrintStatus("ORDER FILLED", fmt.Sprintf("Order %v: %v units at %f", fill.OrderID, fill.Units, fill.Price))
 		}
+	}
 
-	} else if bot.currentPosition.Position == neutral { //If there is no opend position it can open one
-
-		low, high := utils.GetHighLow(bot.currentArea.High, bot.currentArea.Low)
-
-		//If the price is under the current area there is a possible short
-		if candle.Close < low && (low-candle.Close) >= minDifference {
-			bot.currentArea.Close = 0 //Need to find another area to condsider
-			tp := bot.findNextInterestingLevel(candle.Close, short) + minDifference*0.2
-			sl := candle.Close + minDifference*1.5
-			if tp > 0 && math.Abs(float64(candle.Close)-float64(tp)) > math.Abs(float64(candle.Close)-float64(sl))-(float64(minDifference)*1.1) {
-				fmt.Printf("Price under the area --> Opening short position\nStoploss: %v    takeProfit: %v    units: %v\n", sl, tp, bot.CurrentMoney/candle.Close)
-				bot.currentPosition.Position = short
-				bot.currentPosition.StopLoss = sl
-				bot.currentPosition.TakeProfit = tp
-				bot.currentPosition.BuyPrice = candle.Close
-				bot.currentPosition.Units = bot.CurrentMoney / candle.Close
-			}
-		} else if candle.Close > high && (candle.Close-high) >= minDifference { //Else if the price is on top of the current area there is a possible long
-			bot.currentArea.Close = 0 // Need to find another area to condsider
-			tp := bot.findNextInterestingLevel(candle.Close, long) - minDifference*0.2
-			sl := candle.Close - minDifference*1.5
-			if tp > 0 && math.Abs(float64(candle.Close)-float64(tp)) > math.Abs(float64(candle.Close)-float64(sl))-(float64(minDifference)*1.1) {
-				fmt.Printf("Price over the area --> Opening long position\nStoploss: %v    takeProfit: %v    units: %v\n", sl, tp, bot.CurrentMoney/candle.Close)
-
-				bot.currentPosition.Position = long
-				bot.currentPosition.StopLoss = sl
-				bot.currentPosition.TakeProfit = tp
-				bot.currentPosition.BuyPrice = candle.Close
-				bot.currentPosition.Units = bot.CurrentMoney / candle.Close
+	bot.trackCandle(candle)
+
+	//Check if the candle has reached the stopLoss or the takeProfit
+	//A position that scales out can reach more take profits in the same candle,
+	//if a tranche can't be closed the next candle tries again
+	for {
+		price, reason, ok := bot.checkExit(candle)
+		if !ok {
+			break
+		}
+		if reason == takeProfitReason && bot.currentPosition.scalesOut() {
+			if bot.closeTranche(price, candle.Timestamp) {
+				continue
 			}
+			break
 		}
+		bot.closePosition(price, candle.Timestamp, reason)
+		break
+	}
+
+	//Move the stopLoss of the still opened position
+	bot.updateStopLoss(candle)
 
+	for _, signal := range bot.Strategy.OnCandle(ctx, candle, bot.currentPosition.Position) {
+		bot.execute(signal, candle.Timestamp)
 	}
 
 }
 
-// Find the next interesting levels for the take profit
-// If the position is long it search for the first level + delta  > value from the first to the last
-// If the position is short it search for the first level < value + delta from the last to the first
-func (bot *Bot) findNextInterestingLevel(value float32, position int8) float32 {
+// Execute a signal of the strategy placing a market order at the signal price
+// An Open signal is ignored if there is already an opened position, a Close signal if there is none
+func (bot *Bot) execute(signal strategy.Signal, timestamp int64) {
+	switch signal.Action {
+	case strategy.Open:
+		if bot.currentPosition.Position != strategy.Neutral {
+			return
+		}
+
+		units := bot.positionUnits(signal)
+		if units <= 0 {
+			bot.log().Println("The sizer retrieved no units, signal ignored")
+			return
+		}
 
-	delta := float32(50.0)
+		fill, err := bot.Broker.PlaceOrder(broker.Order{
+			Symbol:    bot.Symbol,
+			Side:      signal.Side,
+			Type:      broker.Market,
+			Units:     units,
+			Price:     signal.Price,
+			Timestamp: timestamp,
+		})
+		if err != nil {
+			bot.log().Println("Cannot open the position:", err)
+			return
+		}
 
-	switch position {
-	case short:
+		side := "long"
+		if signal.Side == strategy.Short {
+			side = "short"
+		}
+		bot.log().Printf("Opening %v position\nStoploss: %v    takeProfit: %v    units: %v\n", side, signal.StopLoss, signal.TakeProfit, fill.Units)
+
+		bot.currentPosition = Position{
+			Position:        signal.Side,
+			StopLoss:        signal.StopLoss,
+			InitialStopLoss: signal.StopLoss,
+			TakeProfit:      signal.TakeProfit,
+			BuyPrice:        fill.Price,
+			Units:           fill.Units,
+			InitialUnits:    fill.Units,
+			EntryTimestamp:  fill.Timestamp,
+			EntryFee:        fill.Fee,
+			EntrySlippage:   fill.Slippage,
+			Area:            signal.Area,
+		}
 
-		for i := len(bot.Collection.KeyLevels) - 1; i >= 0; i-- {
-			if bot.Collection.KeyLevels[i]+delta < value {
-				return float32(bot.Collection.KeyLevels[i])
+		if len(bot.Ladder) > 0 {
+			takeProfits := signal.TakeProfits
+			if len(takeProfits) == 0 {
+				takeProfits = []float32{signal.TakeProfit}
 			}
+			bot.currentPosition.Tranches = buildTranches(bot.Ladder, takeProfits, fill.Units)
+			bot.currentPosition.TakeProfit = bot.currentPosition.Tranches[0].TakeProfit
 		}
-		return 0
-	case long:
-		for i := 0; i < len(bot.Collection.KeyLevels); i++ {
-			if bot.Collection.KeyLevels[i] > value+delta {
-				return float32(bot.Collection.KeyLevels[i])
-			}
+	case strategy.Close:
+		if bot.currentPosition.Position != strategy.Neutral {
+			bot.closePosition(signal.Price, timestamp, signalReason)
 		}
-		return 0
-	default:
-		return 0
-
 	}
-
 }
 
-// Update the current daily candle checking if the day has gone
-// Or if there is a new High or a new Low
-func (bot *Bot) updateCurrentDailyCandle(value float32, present time.Time) {
-	currDay := time.Unix(bot.currentDayCandle.Timestamp, 0)
-
-	if sub := present.Sub(currDay); sub.Abs().Hours() >= 24 {
-		bot.currentDayCandle.Close = value
-
-		//If it's not the first day candle
-		if bot.currentDayCandle.Timestamp != 0 {
-			utils.PrintStatus("NEW CANDLE APPENDED", bot.currentDayCandle.ToString())
-			bot.Collection.History = append(bot.Collection.History, bot.currentDayCandle)
-		}
-
-		bot.Collection.FindInterestingAreasAndKeyLevels()
+// Retrieve the money the bot can use to open a position
+// During a portfolio replay it is computed on the balance at the start of the step
+func (bot *Bot) capital() float32 {
+	if bot.inStep {
+		return bot.stepBalance * bot.Allocation
+	}
+	return bot.Broker.Balance() * bot.Allocation
+}
 
-		bot.currentDayCandle = data.Candle{
-			Open:      value,
-			Timestamp: present.Unix(),
-			High:      0,
-			Low:       0xFFFFFF,
-		}
+// Retrieve the ably channel streaming the price of the symbol
+// Only crypto pairs have a default channel (coindesk pricing in USD)
+func (bot *Bot) liveChannel() (string, error) {
+	if bot.Channel != "" {
+		return bot.Channel, nil
+	}
 
-	} else {
+	symbol := api.ParseSymbol(bot.Symbol)
+	if !symbol.IsCrypto() {
+		return "", fmt.Errorf("NO LIVE CHANNEL FOR %v, SET IT EXPLICITLY", bot.Symbol)
+	}
 
-		if value > bot.currentDayCandle.High {
-			bot.currentDayCandle.High = value
-		} else if value < bot.currentDayCandle.Low {
-			bot.currentDayCandle.Low = value
-		}
+	base := strings.ToLower(symbol.Base())
+	if base == "btc" {
+		return "[product:ably-coindesk/bitcoin]bitcoin:usd", nil
 	}
+	return "[product:ably-coindesk/crypto-pricing]" + base + ":usd", nil
 }
 
-// Stream BTC price data using ably package and call the predict on that
-func (bot *Bot) Run() {
+// Stream the price data of the symbol using ably package and call the predict on that
+func (bot *Bot) Run() error {
+	channelName, err := bot.liveChannel()
+	if err != nil {
+		return err
+	}
+
+	key := bot.AblyKey
+	if key == "" {
+		key = defaultAblyKey
+	}
 	client, err := ably.NewRealtime(
-		ably.WithKey("TsoT_A.ll-gaA:PPOPgVew_cMvzi_SrVd_QbuQvm_u_puG1IYMQVjR0S0"),
+		ably.WithKey(key),
 		ably.WithAutoConnect(false),
 	)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	client.Connect()
-	channel := client.Channels.Get("[product:ably-coindesk/bitcoin]bitcoin:usd")
+	channel := client.Channels.Get(channelName)
 
 	_, err = channel.SubscribeAll(context.Background(), func(msg *ably.Message) {
 
 		price, ok := msg.Data.(string)
 		if !ok {
-			fmt.Println("Cannot convert the value in string")
+			bot.log().Println("Cannot convert the value in string")
 			return
 		}
 		value, err := strconv.ParseFloat(price, 32)
 		if err != nil {
-			fmt.Println("Cannot convert the value in float32")
+			bot.log().Println("Cannot convert the value in float32")
 			return
 		}
 
@@ -300,15 +430,15 @@ func (bot *Bot) Run() {
 			Timestamp: present.Unix(),
 		}
 
-		bot.Predict(candle, present)
+		bot.Predict(context.Background(), candle)
 
 	})
 
 	if err != nil {
-		err := fmt.Errorf("subscribing to channel: %w", err)
-		fmt.Println(err)
+		return fmt.Errorf("subscribing to channel: %w", err)
 	}
 
 	//Used to never stop listening to price data
 	time.Sleep(time.Hour * 0xFFFFF)
+	return nil
 }
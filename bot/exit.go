@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"fmt"
+
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+// How to exit when a candle touches both the stopLoss and the takeProfit
+// The candle doesn't tell which level was reached first
+type BothHitRule int8
+
+const (
+	StopLossFirst   BothHitRule = iota //Pessimistic, assume the stopLoss was reached first
+	TakeProfitFirst                    //Optimistic, assume the takeProfit was reached first
+	NearestToOpen                      //Assume the level closer to the open was reached first
+)
+
+// Parse the rule: stop-loss, take-profit or nearest
+func ParseBothHitRule(value string) (BothHitRule, error) {
+	switch value {
+	case "stop-loss":
+		return StopLossFirst, nil
+	case "take-profit":
+		return TakeProfitFirst, nil
+	case "nearest":
+		return NearestToOpen, nil
+	}
+	return 0, fmt.Errorf("INVALID BOTH HIT RULE %q, USE stop-loss, take-profit OR nearest", value)
+}
+
+// Reasons to close a position
+const (
+	stopLossReason     = "STOP LOSS"
+	trailingStopReason = "TRAILING STOP"
+	takeProfitReason   = "TAKE PROFIT"
+	signalReason       = "SIGNAL"
+	endReason          = "END"
+)
+
+// Check if the candle reached the stopLoss or the takeProfit of the current position
+// The levels are checked against the High and the Low of the candle and the exit is filled at the level,
+// if the candle opened beyond a level (gap) the exit is filled at the open
+// It retrieves the exit price and the reason
+func (bot *Bot) checkExit(candle data.Candle) (float32, string, bool) {
+	position := bot.currentPosition
+	if position.Position == strategy.Neutral {
+		return 0, "", false
+	}
+
+	//The distance of the price from a level in the direction of the position, negative beyond the level
+	side := float32(position.Position)
+	distance := func(price, level float32) float32 { return side * (price - level) }
+
+	stopReason := stopLossReason
+	if position.StopLoss != position.InitialStopLoss {
+		stopReason = trailingStopReason
+	}
+
+	//Gaps
+	if distance(candle.Open, position.StopLoss) <= 0 {
+		return candle.Open, stopReason, true
+	}
+	if distance(candle.Open, position.TakeProfit) >= 0 {
+		return candle.Open, takeProfitReason, true
+	}
+
+	worst, best := candle.Low, candle.High
+	if position.Position == strategy.Short {
+		worst, best = candle.High, candle.Low
+	}
+	hitStopLoss := distance(worst, position.StopLoss) <= 0
+	hitTakeProfit := distance(best, position.TakeProfit) >= 0
+
+	if hitStopLoss && hitTakeProfit {
+		switch bot.BothHit {
+		case TakeProfitFirst:
+			hitStopLoss = false
+		case NearestToOpen:
+			if distance(candle.Open, position.StopLoss) < -distance(candle.Open, position.TakeProfit) {
+				hitTakeProfit = false
+			} else {
+				hitStopLoss = false
+			}
+		default:
+			hitTakeProfit = false
+		}
+	}
+
+	if hitStopLoss {
+		return position.StopLoss, stopReason, true
+	}
+	if hitTakeProfit {
+		return position.TakeProfit, takeProfitReason, true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/sizing"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// A strategy that never signals
+type idleStrategy struct{}
+
+func (idleStrategy) Initialize(history []data.Candle) {}
+
+func (idleStrategy) OnCandle(ctx context.Context, candle data.Candle, position int8) []strategy.Signal {
+	return nil
+}
+
+func almostEqual(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+// Build a quiet bot holding the position opened by the signal
+// If the bot has no broker it uses a paper one, if it has no strategy an idle one
+func openedBot(t *testing.T, bot *Bot, signal strategy.Signal) *Bot {
+	t.Helper()
+	bot.Symbol = "BINANCE:BTCUSDT"
+	if bot.Broker == nil {
+		bot.Broker = broker.NewPaper(10000)
+	}
+	if bot.Strategy == nil {
+		bot.Strategy = idleStrategy{}
+	}
+	bot.Allocation = 1
+	bot.Logger = utils.Discard
+
+	signal.Action = strategy.Open
+	bot.execute(signal, 0)
+	if bot.currentPosition.Position != signal.Side {
+		t.Fatalf("the signal %+v didn't open a position", signal)
+	}
+	return bot
+}
+
+func TestCheckExit(t *testing.T) {
+	long := strategy.Signal{Side: strategy.Long, Price: 100, StopLoss: 90, TakeProfit: 120}
+	short := strategy.Signal{Side: strategy.Short, Price: 100, StopLoss: 110, TakeProfit: 80}
+
+	tests := []struct {
+		name    string
+		signal  strategy.Signal
+		rule    BothHitRule
+		candle  data.Candle
+		price   float32
+		reason  string
+		exiting bool
+	}{
+		{"long inside", long, StopLossFirst, data.Candle{Open: 100, High: 115, Low: 95, Close: 110}, 0, "", false},
+		{"long stop loss", long, StopLossFirst, data.Candle{Open: 100, High: 105, Low: 89, Close: 95}, 90, stopLossReason, true},
+		{"long take profit", long, StopLossFirst, data.Candle{Open: 100, High: 121, Low: 95, Close: 110}, 120, takeProfitReason, true},
+		{"long gap under the stop loss", long, StopLossFirst, data.Candle{Open: 85, High: 95, Low: 80, Close: 92}, 85, stopLossReason, true},
+		{"long gap over the take profit", long, StopLossFirst, data.Candle{Open: 125, High: 130, Low: 85, Close: 128}, 125, takeProfitReason, true},
+		{"long both stop loss first", long, StopLossFirst, data.Candle{Open: 100, High: 125, Low: 85, Close: 100}, 90, stopLossReason, true},
+		{"long both take profit first", long, TakeProfitFirst, data.Candle{Open: 100, High: 125, Low: 85, Close: 100}, 120, takeProfitReason, true},
+		{"long both nearest stop loss", long, NearestToOpen, data.Candle{Open: 95, High: 125, Low: 85, Close: 100}, 90, stopLossReason, true},
+		{"long both nearest take profit", long, NearestToOpen, data.Candle{Open: 115, High: 125, Low: 85, Close: 100}, 120, takeProfitReason, true},
+		{"short inside", short, StopLossFirst, data.Candle{Open: 100, High: 105, Low: 85, Close: 90}, 0, "", false},
+		{"short stop loss", short, StopLossFirst, data.Candle{Open: 100, High: 111, Low: 95, Close: 105}, 110, stopLossReason, true},
+		{"short take profit", short, StopLossFirst, data.Candle{Open: 100, High: 105, Low: 79, Close: 85}, 80, takeProfitReason, true},
+		{"short gap over the stop loss", short, StopLossFirst, data.Candle{Open: 115, High: 120, Low: 105, Close: 118}, 115, stopLossReason, true},
+		{"short gap under the take profit", short, StopLossFirst, data.Candle{Open: 75, High: 78, Low: 70, Close: 72}, 75, takeProfitReason, true},
+		{"short both stop loss first", short, StopLossFirst, data.Candle{Open: 100, High: 115, Low: 75, Close: 100}, 110, stopLossReason, true},
+		{"short both take profit first", short, TakeProfitFirst, data.Candle{Open: 100, High: 115, Low: 75, Close: 100}, 80, takeProfitReason, true},
+		{"short both nearest take profit", short, NearestToOpen, data.Candle{Open: 85, High: 115, Low: 75, Close: 100}, 80, takeProfitReason, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot := openedBot(t, &Bot{BothHit: test.rule}, test.signal)
+			price, reason, exiting := bot.checkExit(test.candle)
+			if price != test.price || reason != test.reason || exiting != test.exiting {
+				t.Fatalf("expected %v %q %v, got %v %q %v", test.price, test.reason, test.exiting, price, reason, exiting)
+			}
+		})
+	}
+}
+
+func TestCheckExitTrailingStopReason(t *testing.T) {
+	bot := openedBot(t, &Bot{}, strategy.Signal{Side: strategy.Long, Price: 100, StopLoss: 90, TakeProfit: 120})
+	bot.currentPosition.StopLoss = 100
+
+	price, reason, exiting := bot.checkExit(data.Candle{Open: 105, High: 110, Low: 99, Close: 101})
+	if price != 100 || reason != trailingStopReason || !exiting {
+		t.Fatalf("expected 100 %q true, got %v %q %v", trailingStopReason, price, reason, exiting)
+	}
+}
+
+func TestPredictClosesWithinTheCandle(t *testing.T) {
+	tests := []struct {
+		name    string
+		candle  data.Candle
+		price   float32
+		reason  string
+		balance float32
+	}{
+		//The take profit rests at its level: maker fee, no slippage
+		{"take profit", data.Candle{Open: 110, High: 125, Low: 105, Close: 115}, 120, takeProfitReason, 10000 + 100*20 - 100*100*0.002 - 100*120*0.001},
+		//The stop loss is a market order from the gap open: taker fee and 1% slippage
+		{"gap under the stop loss", data.Candle{Open: 80, High: 85, Low: 75, Close: 82}, 79.2, stopLossReason, 10000 - 100*20.8 - 100*100*0.002 - 100*79.2*0.002},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paper := broker.NewPaper(10000)
+			paper.Costs = broker.Costs{MakerFee: 0.001, TakerFee: 0.002}
+			bot := openedBot(t, &Bot{Broker: paper, Sizer: fixedUnits(100)}, strategy.Signal{Side: strategy.Long, Price: 100, StopLoss: 90, TakeProfit: 120})
+			//Only the exit slips
+			paper.Costs.FixedSlippage = 0.01
+
+			bot.Predict(context.Background(), test.candle)
+
+			if len(bot.Trades) != 1 {
+				t.Fatalf("expected 1 trade, got %v", len(bot.Trades))
+			}
+			trade := bot.Trades[0]
+			if !almostEqual(trade.ExitPrice, test.price) || trade.ExitReason != test.reason {
+				t.Fatalf("expected an exit at %v (%v), got %v (%v)", test.price, test.reason, trade.ExitPrice, trade.ExitReason)
+			}
+			if bot.currentPosition.Position != strategy.Neutral || len(paper.Positions()) != 0 {
+				t.Fatalf("expected no position, got %+v", paper.Positions())
+			}
+			if !almostEqual(paper.Balance(), test.balance) {
+				t.Fatalf("expected balance %v, got %v", test.balance, paper.Balance())
+			}
+		})
+	}
+}
+
+// A sizer retrieving always the same units
+type fixedUnits float32
+
+func (units fixedUnits) Units(request sizing.Request) float32 {
+	return float32(units)
+}
@@ -0,0 +1,488 @@
+package bot
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+// The interval of the JSON rewrites when it is 0
+const defaultJournalInterval = time.Minute
+
+// The trade journal writes every trade to its files as soon as it is closed
+// Every path is a CSV or JSON file, the format is chosen from the extension
+// A CSV file gets a row appended for every trade (with the header first if the file is new or empty), while a JSON file
+// is a single array: it is rewritten at most once every Interval (a minute if 0) and by Close, so call it before exiting
+// The trades already in the files are kept, so a restarted bot goes on with the same journal
+// It is safe for concurrent use, so the bots of a portfolio can share it
+type Journal struct {
+	Paths    []string
+	Interval time.Duration
+
+	mu       sync.Mutex
+	trades   []Trade
+	previous map[string][]Trade //The trades of every JSON file before the first rewrite
+	timer    *time.Timer        //Pending rewrite of the JSON files
+	err      error              //Failure of the last pending rewrite
+}
+
+// Build a journal checking the format of the paths
+func NewJournal(paths ...string) (*Journal, error) {
+	for _, path := range paths {
+		if err := CheckJournalPath(path); err != nil {
+			return nil, err
+		}
+	}
+	return &Journal{Paths: paths}, nil
+}
+
+// Check that the extension of the path is a journal format, .csv or .json in any case
+func CheckJournalPath(path string) error {
+	_, err := tradesWriter(path)
+	return err
+}
+
+// Add the trade to the journal, append it to the CSV files and schedule the rewrite of the JSON ones
+// It also retrieves the failure of the previous rewrite if any
+func (journal *Journal) Record(trade Trade) error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	journal.trades = append(journal.trades, trade)
+	for _, path := range journal.Paths {
+		if isJSON(path) {
+			continue
+		}
+		if err := appendCSV(path, trade); err != nil {
+			return err
+		}
+	}
+
+	if journal.timer == nil && journal.hasJSON() {
+		interval := journal.Interval
+		if interval <= 0 {
+			interval = defaultJournalInterval
+		}
+		journal.timer = time.AfterFunc(interval, journal.flush)
+	}
+
+	err := journal.err
+	journal.err = nil
+	return err
+}
+
+// Write the given trades to the files of the journal, replacing their content
+// Used to save the trades of a backtest at once
+func (journal *Journal) Write(trades []Trade) error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	journal.stopTimer()
+	journal.trades = append([]Trade(nil), trades...)
+	journal.previous = make(map[string][]Trade)
+	for _, path := range journal.Paths {
+		if isJSON(path) {
+			journal.previous[path] = nil
+		}
+		if err := SaveTrades(path, journal.trades); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewrite the JSON files if a rewrite is pending
+func (journal *Journal) Close() error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	if journal.stopTimer() {
+		if err := journal.writeJSON(); err != nil {
+			return err
+		}
+	}
+	err := journal.err
+	journal.err = nil
+	return err
+}
+
+// Rewrite the JSON files when the timer fires, the error is retrieved by the next Record or Close
+func (journal *Journal) flush() {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	journal.timer = nil
+	if err := journal.writeJSON(); err != nil {
+		journal.err = err
+	}
+}
+
+// Stop the pending rewrite, it retrieves whether there was one
+func (journal *Journal) stopTimer() bool {
+	if journal.timer == nil {
+		return false
+	}
+	journal.timer.Stop()
+	journal.timer = nil
+	return true
+}
+
+// Rewrite the JSON files with the trades they had before the first rewrite followed by the recorded ones
+func (journal *Journal) writeJSON() error {
+	if journal.previous == nil {
+		journal.previous = make(map[string][]Trade)
+	}
+
+	for _, path := range journal.Paths {
+		if !isJSON(path) {
+			continue
+		}
+		previous, loaded := journal.previous[path]
+		if !loaded {
+			var err error
+			if previous, err = LoadTrades(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			journal.previous[path] = previous
+		}
+
+		trades := append(append([]Trade(nil), previous...), journal.trades...)
+		if err := SaveTrades(path, trades); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (journal *Journal) hasJSON() bool {
+	for _, path := range journal.Paths {
+		if isJSON(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Append the row of the trade to the CSV file, a new or empty file gets the header first
+func appendCSV(path string, trade Trade) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	if info.Size() == 0 {
+		writer.Write(journalHeader)
+	}
+	writer.Write(toJournalEntry(trade).record())
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func isJSON(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".json"
+}
+
+// The journal representation of a trade
+// The timestamps are also reported as RFC 3339 times, the area is the band between its low and its high
+type journalEntry struct {
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	EntryTimestamp  int64   `json:"entryTimestamp"`
+	EntryTime       string  `json:"entryTime"`
+	ExitTimestamp   int64   `json:"exitTimestamp"`
+	ExitTime        string  `json:"exitTime"`
+	EntryPrice      float32 `json:"entryPrice"`
+	ExitPrice       float32 `json:"exitPrice"`
+	Units           float32 `json:"units"`
+	InitialStopLoss float32 `json:"initialStopLoss"`
+	StopLoss        float32 `json:"stopLoss"`
+	TakeProfit      float32 `json:"takeProfit"`
+	ExitReason      string  `json:"exitReason"`
+	EntryFee        float32 `json:"entryFee"`
+	ExitFee         float32 `json:"exitFee"`
+	Slippage        float32 `json:"slippage"`
+	Funding         float32 `json:"funding"`
+	GrossPL         float32 `json:"grossPL"`
+	NetPL           float32 `json:"netPL"`
+	AreaTimestamp   int64   `json:"areaTimestamp"`
+	AreaLow         float32 `json:"areaLow"`
+	AreaHigh        float32 `json:"areaHigh"`
+}
+
+var journalHeader = []string{
+	"symbol", "side", "entry_timestamp", "entry_time", "exit_timestamp", "exit_time",
+	"entry_price", "exit_price", "units", "initial_stop_loss", "stop_loss", "take_profit", "exit_reason",
+	"entry_fee", "exit_fee", "slippage", "funding", "gross_pl", "net_pl",
+	"area_timestamp", "area_low", "area_high",
+}
+
+func toJournalEntry(trade Trade) journalEntry {
+	side := "LONG"
+	if trade.Side < 0 {
+		side = "SHORT"
+	}
+
+	return journalEntry{
+		Symbol:          trade.Symbol,
+		Side:            side,
+		EntryTimestamp:  trade.EntryTimestamp,
+		EntryTime:       time.Unix(trade.EntryTimestamp, 0).UTC().Format(time.RFC3339),
+		ExitTimestamp:   trade.ExitTimestamp,
+		ExitTime:        time.Unix(trade.ExitTimestamp, 0).UTC().Format(time.RFC3339),
+		EntryPrice:      trade.EntryPrice,
+		ExitPrice:       trade.ExitPrice,
+		Units:           trade.Units,
+		InitialStopLoss: trade.InitialStopLoss,
+		StopLoss:        trade.StopLoss,
+		TakeProfit:      trade.TakeProfit,
+		ExitReason:      trade.ExitReason,
+		EntryFee:        trade.EntryFee,
+		ExitFee:         trade.ExitFee,
+		Slippage:        trade.Slippage,
+		Funding:         trade.Funding,
+		GrossPL:         trade.GrossPL,
+		NetPL:           trade.NetPL,
+		AreaTimestamp:   trade.Area.Timestamp,
+		AreaLow:         trade.Area.Low,
+		AreaHigh:        trade.Area.High,
+	}
+}
+
+// Convert the journal representation back to a trade
+func (entry journalEntry) trade() (Trade, error) {
+	var side int8
+	switch entry.Side {
+	case "LONG":
+		side = 1
+	case "SHORT":
+		side = -1
+	default:
+		return Trade{}, fmt.Errorf("INVALID SIDE %q, USE LONG OR SHORT", entry.Side)
+	}
+
+	return Trade{
+		Symbol:          entry.Symbol,
+		Side:            side,
+		EntryTimestamp:  entry.EntryTimestamp,
+		ExitTimestamp:   entry.ExitTimestamp,
+		EntryPrice:      entry.EntryPrice,
+		ExitPrice:       entry.ExitPrice,
+		Units:           entry.Units,
+		InitialStopLoss: entry.InitialStopLoss,
+		StopLoss:        entry.StopLoss,
+		TakeProfit:      entry.TakeProfit,
+		ExitReason:      entry.ExitReason,
+		EntryFee:        entry.EntryFee,
+		ExitFee:         entry.ExitFee,
+		Slippage:        entry.Slippage,
+		Funding:         entry.Funding,
+		GrossPL:         entry.GrossPL,
+		NetPL:           entry.NetPL,
+		Area:            data.Candle{Timestamp: entry.AreaTimestamp, Low: entry.AreaLow, High: entry.AreaHigh},
+	}, nil
+}
+
+func (entry journalEntry) record() []string {
+	float := func(value float32) string { return strconv.FormatFloat(float64(value), 'f', -1, 32) }
+
+	return []string{
+		entry.Symbol, entry.Side,
+		strconv.FormatInt(entry.EntryTimestamp, 10), entry.EntryTime,
+		strconv.FormatInt(entry.ExitTimestamp, 10), entry.ExitTime,
+		float(entry.EntryPrice), float(entry.ExitPrice), float(entry.Units),
+		float(entry.InitialStopLoss), float(entry.StopLoss), float(entry.TakeProfit), entry.ExitReason,
+		float(entry.EntryFee), float(entry.ExitFee), float(entry.Slippage), float(entry.Funding),
+		float(entry.GrossPL), float(entry.NetPL),
+		strconv.FormatInt(entry.AreaTimestamp, 10), float(entry.AreaLow), float(entry.AreaHigh),
+	}
+}
+
+// Parse a CSV row of the journal, the columns are the ones of the header
+func parseJournalRecord(record []string) (journalEntry, error) {
+	var err error
+	integer := func(value string) int64 {
+		parsed, parseErr := strconv.ParseInt(value, 10, 64)
+		if parseErr != nil && err == nil {
+			err = fmt.Errorf("INVALID NUMBER %q", value)
+		}
+		return parsed
+	}
+	float := func(value string) float32 {
+		parsed, parseErr := strconv.ParseFloat(value, 32)
+		if parseErr != nil && err == nil {
+			err = fmt.Errorf("INVALID NUMBER %q", value)
+		}
+		return float32(parsed)
+	}
+
+	entry := journalEntry{
+		Symbol:          record[0],
+		Side:            record[1],
+		EntryTimestamp:  integer(record[2]),
+		EntryTime:       record[3],
+		ExitTimestamp:   integer(record[4]),
+		ExitTime:        record[5],
+		EntryPrice:      float(record[6]),
+		ExitPrice:       float(record[7]),
+		Units:           float(record[8]),
+		InitialStopLoss: float(record[9]),
+		StopLoss:        float(record[10]),
+		TakeProfit:      float(record[11]),
+		ExitReason:      record[12],
+		EntryFee:        float(record[13]),
+		ExitFee:         float(record[14]),
+		Slippage:        float(record[15]),
+		Funding:         float(record[16]),
+		GrossPL:         float(record[17]),
+		NetPL:           float(record[18]),
+		AreaTimestamp:   integer(record[19]),
+		AreaLow:         float(record[20]),
+		AreaHigh:        float(record[21]),
+	}
+	return entry, err
+}
+
+// Read the trades written by WriteTradesCSV
+func ReadTradesCSV(r io.Reader) ([]Trade, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(journalHeader)
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(journalHeader, ",") {
+		return nil, fmt.Errorf("UNEXPECTED JOURNAL HEADER %v", strings.Join(records[0], ","))
+	}
+
+	var trades []Trade
+	for i, record := range records[1:] {
+		entry, err := parseJournalRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", i+2, err)
+		}
+		trade, err := entry.trade()
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", i+2, err)
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+// Read the trades written by WriteTradesJSON
+func ReadTradesJSON(r io.Reader) ([]Trade, error) {
+	var entries []journalEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	trades := make([]Trade, 0, len(entries))
+	for i, entry := range entries {
+		trade, err := entry.trade()
+		if err != nil {
+			return nil, fmt.Errorf("trade %v: %w", i, err)
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+// Read the trades of a CSV or JSON journal file, choosing the format from the extension
+func LoadTrades(path string) ([]Trade, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var trades []Trade
+	if isJSON(path) {
+		trades, err = ReadTradesJSON(file)
+	} else {
+		trades, err = ReadTradesCSV(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %v: %w", path, err)
+	}
+	return trades, nil
+}
+
+// Write the trades as CSV with a header
+func WriteTradesCSV(w io.Writer, trades []Trade) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(journalHeader); err != nil {
+		return err
+	}
+	for _, trade := range trades {
+		if err := writer.Write(toJournalEntry(trade).record()); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Write the trades as a JSON array
+func WriteTradesJSON(w io.Writer, trades []Trade) error {
+	entries := make([]journalEntry, 0, len(trades))
+	for _, trade := range trades {
+		entries = append(entries, toJournalEntry(trade))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// Retrieve the writer of the format of the file from its extension
+func tradesWriter(path string) (func(io.Writer, []Trade) error, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return WriteTradesCSV, nil
+	case ".json":
+		return WriteTradesJSON, nil
+	}
+	return nil, fmt.Errorf("UNSUPPORTED JOURNAL FORMAT %q, USE .csv OR .json", filepath.Ext(path))
+}
+
+// Write the trades to a CSV or JSON file, choosing the format from the extension
+func SaveTrades(path string, trades []Trade) error {
+	write, err := tradesWriter(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, trades); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
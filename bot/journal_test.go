@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+func journalTrades() []Trade {
+	return []Trade{
+		{
+			Symbol: "BINANCE:BTCUSDT", Side: 1, EntryTimestamp: 1640995200, ExitTimestamp: 1641000600,
+			EntryPrice: 47000, ExitPrice: 47500.5, Units: 0.25, InitialStopLoss: 46550, StopLoss: 47000, TakeProfit: 47600,
+			ExitReason: "take-profit", EntryFee: 11.75, ExitFee: 11.875, Slippage: 0.5, GrossPL: 125.125, NetPL: 101.5,
+			Area: data.Candle{Timestamp: 1640908800, Low: 46000, High: 46700},
+		},
+		{
+			Symbol: "BINANCE:ETHUSDT", Side: -1, EntryTimestamp: 1641081600, ExitTimestamp: 1641168000,
+			EntryPrice: 3800, ExitPrice: 3900, Units: 2, InitialStopLoss: 3900, StopLoss: 3900, TakeProfit: 3600,
+			ExitReason: "stop-loss", Funding: 0.76, GrossPL: -200, NetPL: -200.76,
+			Area: data.Candle{Timestamp: 1640995200, Low: 3820, High: 3950},
+		},
+	}
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	for _, name := range []string{"trades.csv", "trades.json", "TRADES.JSON"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := SaveTrades(path, journalTrades()); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadTrades(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, journalTrades()) {
+				t.Fatalf("got %+v, expected %+v", got, journalTrades())
+			}
+		})
+	}
+}
+
+func TestJournalAppendsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	csvPath, jsonPath := filepath.Join(dir, "trades.csv"), filepath.Join(dir, "trades.json")
+	trades := journalTrades()
+
+	for _, trade := range trades {
+		journal, err := NewJournal(csvPath, jsonPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := journal.Record(trade); err != nil {
+			t.Fatal(err)
+		}
+		if err := journal.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	raw, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers := strings.Count(string(raw), "symbol,side"); headers != 1 {
+		t.Fatalf("expected one header in the CSV, got %v", headers)
+	}
+	for _, path := range []string{csvPath, jsonPath} {
+		got, err := LoadTrades(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, trades) {
+			t.Fatalf("%v: got %+v, expected %+v", path, got, trades)
+		}
+	}
+}
+
+func TestJournalRewritesTheJSONOnTheTimer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trades.json")
+	journal := &Journal{Paths: []string{path}, Interval: 20 * time.Millisecond}
+
+	if err := journal.Record(journalTrades()[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("the JSON must wait for the interval, got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if trades, err := LoadTrades(path); err == nil && len(trades) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the JSON was not rewritten after the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJournalWriteReplacesTheFiles(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "trades.csv"), filepath.Join(dir, "trades.json")}
+	for _, path := range paths {
+		if err := SaveTrades(path, journalTrades()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	journal, err := NewJournal(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Write(journalTrades()[:1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		got, err := LoadTrades(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("%v: expected the single written trade, got %v", path, len(got))
+		}
+	}
+}
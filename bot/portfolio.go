@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+// The portfolio hosts one bot for every symbol, sorted by symbol
+// All the bots share the same paper broker, every bot can use its allocation (a fraction) of the balance
+// If set, BeforeStep and AfterStep are called by the Replay before and after predicting all the candles with the same timestamp
+type Portfolio struct {
+	Broker                *broker.Paper
+	Bots                  []*Bot
+	BeforeStep, AfterStep func(timestamp int64, candles map[string]data.Candle)
+}
+
+// Initialize a bot for every symbol of the allocations map using the daily history from the provider
+// The strategies function builds the strategy of every symbol, if nil the bots use the breakout strategy
+// The allocations must be positive and their sum can't exceed 1
+func NewPortfolio(provider api.CandleProvider, strategies func(symbol string) strategy.Strategy, initialAmount float32, allocations map[string]float32, from, to int64) (*Portfolio, error) {
+	if initialAmount <= 0 {
+		return nil, fmt.Errorf("INITIAL AMOUNT MUST BE POSITIVE")
+	}
+	if len(allocations) == 0 {
+		return nil, fmt.Errorf("PORTFOLIO MUST CONTAIN AT LEAST ONE SYMBOL")
+	}
+
+	var total float32
+	symbols := make([]string, 0, len(allocations))
+	for symbol, allocation := range allocations {
+		if allocation <= 0 {
+			return nil, fmt.Errorf("ALLOCATION OF %v MUST BE POSITIVE", symbol)
+		}
+		total += allocation
+		symbols = append(symbols, symbol)
+	}
+	if total > 1.0001 {
+		return nil, fmt.Errorf("THE SUM OF THE ALLOCATIONS (%v) EXCEEDS 1", total)
+	}
+	sort.Strings(symbols)
+
+	portfolio := &Portfolio{Broker: broker.NewPaper(initialAmount)}
+	for _, symbol := range symbols {
+		symbolBot := &Bot{Provider: provider}
+		if strategies != nil {
+			symbolBot.Strategy = strategies(symbol)
+		}
+		if err := symbolBot.initialize(symbol, portfolio.Broker, allocations[symbol], from, to); err != nil {
+			return nil, fmt.Errorf("initializing %v: %w", symbol, err)
+		}
+		portfolio.Bots = append(portfolio.Bots, symbolBot)
+	}
+
+	return portfolio, nil
+}
+
+// Stream the live price of every symbol, each bot runs in its own goroutine
+// It returns when all the bots stop, with the first error
+func (portfolio *Portfolio) Run() error {
+	errs := make(chan error, len(portfolio.Bots))
+
+	var wg sync.WaitGroup
+	for _, symbolBot := range portfolio.Bots {
+		wg.Add(1)
+		go func(symbolBot *Bot) {
+			defer wg.Done()
+			if err := symbolBot.Run(); err != nil {
+				errs <- fmt.Errorf("%v: %w", symbolBot.Symbol, err)
+			}
+		}(symbolBot)
+	}
+
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// Replay the candles of every symbol
+// The bots move in lockstep: all the candles with the same timestamp are predicted before moving to the next one,
+// so no bot sees a balance changed by a candle from its future
+// The bots of a step size their positions on the balance at the start of the step and predict one after the other
+// in the order of their symbols, so the fills are applied in the same order and the replay is reproducible
+func (portfolio *Portfolio) Replay(ctx context.Context, candles map[string][]data.Candle) {
+	traded := make(map[string]bool, len(portfolio.Bots))
+	for _, symbolBot := range portfolio.Bots {
+		traded[symbolBot.Symbol] = true
+	}
+
+	//Group the candles of the traded symbols by timestamp
+	byTimestamp := make(map[int64]map[string]data.Candle)
+	for symbol, symbolCandles := range candles {
+		if !traded[symbol] {
+			continue
+		}
+		for _, candle := range symbolCandles {
+			if byTimestamp[candle.Timestamp] == nil {
+				byTimestamp[candle.Timestamp] = make(map[string]data.Candle)
+			}
+			byTimestamp[candle.Timestamp][symbol] = candle
+		}
+	}
+
+	timestamps := make([]int64, 0, len(byTimestamp))
+	for timestamp := range byTimestamp {
+		timestamps = append(timestamps, timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	for _, timestamp := range timestamps {
+		stepCandles := byTimestamp[timestamp]
+		if portfolio.BeforeStep != nil {
+			portfolio.BeforeStep(timestamp, stepCandles)
+		}
+
+		balance := portfolio.Broker.Balance()
+		for _, symbolBot := range portfolio.Bots { //Sorted by symbol
+			candle, ok := stepCandles[symbolBot.Symbol]
+			if !ok { //The symbol has no candle at this time
+				continue
+			}
+			symbolBot.stepBalance, symbolBot.inStep = balance, true
+			symbolBot.Predict(ctx, candle)
+			symbolBot.inStep = false
+		}
+
+		if portfolio.AfterStep != nil {
+			portfolio.AfterStep(timestamp, stepCandles)
+		}
+	}
+}
+
+// Print the status of every bot
+func (portfolio *Portfolio) Print() {
+	for _, symbolBot := range portfolio.Bots {
+		symbolBot.Print()
+	}
+}
@@ -1,6 +1,95 @@
 package bot
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+// The position opened by the bot
+// The StopLoss can be moved by the trailing stop, the InitialStopLoss is the one of the signal
+// EntryTimestamp, EntryFee and EntrySlippage come from the opening fill and end up in the trade records
+// The Units are the remaining units, the InitialUnits the opened ones
+// If the position scales out the Tranches are the take profit ladder and the TakeProfit is the one of the next open tranche
+// RealisedPL is the net profit/loss of the units already closed
+// Area is the interest area that triggered the signal
 type Position struct {
 	Position                              int8
 	StopLoss, TakeProfit, BuyPrice, Units float32
+	InitialStopLoss, InitialUnits         float32
+	EntryTimestamp                        int64
+	EntryFee, EntrySlippage               float32
+	Tranches                              []Tranche
+	RealisedPL                            float32
+	Area                                  data.Candle
+}
+
+// A part of the position closed at its own TakeProfit
+// When it is Closed the ExitPrice and the net PL are set
+type Tranche struct {
+	TakeProfit, Units float32
+	Closed            bool
+	ExitPrice, PL     float32
+}
+
+// Split the units into tranches closed at the successive take profits
+// The ladder contains the fraction of the units of every tranche
+// If there are fewer take profits than fractions the exceeding fractions go to the last tranche
+func buildTranches(ladder, takeProfits []float32, units float32) []Tranche {
+	n := len(ladder)
+	if len(takeProfits) < n {
+		n = len(takeProfits)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	tranches := make([]Tranche, n)
+	var assigned float32
+	for i := 0; i < n-1; i++ {
+		tranches[i] = Tranche{TakeProfit: takeProfits[i], Units: ladder[i] * units}
+		assigned += tranches[i].Units
+	}
+	//The last tranche takes the rest, including the rounding errors
+	tranches[n-1] = Tranche{TakeProfit: takeProfits[n-1], Units: units - assigned}
+
+	return tranches
+}
+
+// Retrieve the index of the first open tranche, -1 if there is none
+func (position *Position) nextTranche() int {
+	for i, tranche := range position.Tranches {
+		if !tranche.Closed {
+			return i
+		}
+	}
+	return -1
+}
+
+// Check if after the next open tranche there are other open tranches
+func (position *Position) scalesOut() bool {
+	next := position.nextTranche()
+	return next != -1 && next < len(position.Tranches)-1
+}
+
+// Parse a take profit ladder, a comma separated list of fractions of the position summing to 1 (e.g. 0.5,0.3,0.2)
+func ParseLadder(value string) ([]float32, error) {
+	var ladder []float32
+	var total float32
+
+	for _, fraction := range strings.Split(value, ",") {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(fraction), 32)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("INVALID LADDER FRACTION %q, IT MUST BE A POSITIVE NUMBER", fraction)
+		}
+		ladder = append(ladder, float32(parsed))
+		total += float32(parsed)
+	}
+
+	if total < 0.999 || total > 1.001 {
+		return nil, fmt.Errorf("THE LADDER FRACTIONS MUST SUM TO 1, THEY SUM TO %v", total)
+	}
+	return ladder, nil
 }
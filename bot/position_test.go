@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+func TestBuildTranches(t *testing.T) {
+	tests := []struct {
+		name        string
+		ladder      []float32
+		takeProfits []float32
+		expected    []Tranche
+	}{
+		{"one per take profit", []float32{0.5, 0.25, 0.25}, []float32{110, 120, 130}, []Tranche{{TakeProfit: 110, Units: 50}, {TakeProfit: 120, Units: 25}, {TakeProfit: 130, Units: 25}}},
+		{"fewer take profits", []float32{0.5, 0.25, 0.25}, []float32{110, 120}, []Tranche{{TakeProfit: 110, Units: 50}, {TakeProfit: 120, Units: 50}}},
+		{"more take profits", []float32{0.5, 0.5}, []float32{110, 120, 130}, []Tranche{{TakeProfit: 110, Units: 50}, {TakeProfit: 120, Units: 50}}},
+		{"no take profits", []float32{0.5, 0.5}, nil, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := buildTranches(test.ladder, test.takeProfits, 100); !reflect.DeepEqual(got, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseLadder(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []float32
+		fails    bool
+	}{
+		{"1", []float32{1}, false},
+		{"0.5, 0.3,0.2", []float32{0.5, 0.3, 0.2}, false},
+		{"0.5,0.3", nil, true},
+		{"0.5,0.6", nil, true},
+		{"0.5,-0.5,1", nil, true},
+		{"half,half", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			ladder, err := ParseLadder(test.value)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", ladder)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ladder, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, ladder)
+			}
+		})
+	}
+}
+
+func TestLadderTrancheAccounting(t *testing.T) {
+	type exit struct {
+		reason     string
+		units      float32
+		price      float32
+		takeProfit float32
+	}
+
+	tests := []struct {
+		name    string
+		candles []data.Candle
+		exits   []exit
+		left    float32
+	}{
+		{
+			"first tranche",
+			[]data.Candle{{Open: 100, High: 112, Low: 99, Close: 108, Timestamp: 60}},
+			[]exit{{takeProfitReason, 50, 110, 110}},
+			50,
+		},
+		{
+			"two tranches in a candle",
+			[]data.Candle{{Open: 100, High: 125, Low: 99, Close: 115, Timestamp: 60}},
+			[]exit{{takeProfitReason, 50, 110, 110}, {takeProfitReason, 25, 120, 120}},
+			25,
+		},
+		{
+			"all the tranches",
+			[]data.Candle{{Open: 100, High: 125, Low: 99, Close: 115, Timestamp: 60}, {Open: 115, High: 135, Low: 114, Close: 130, Timestamp: 120}},
+			[]exit{{takeProfitReason, 50, 110, 110}, {takeProfitReason, 25, 120, 120}, {takeProfitReason, 25, 130, 130}},
+			0,
+		},
+		{
+			"stop loss after a tranche",
+			[]data.Candle{{Open: 100, High: 112, Low: 99, Close: 108, Timestamp: 60}, {Open: 100, High: 101, Low: 85, Close: 88, Timestamp: 120}},
+			[]exit{{takeProfitReason, 50, 110, 110}, {stopLossReason, 50, 90, 120}},
+			0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paper := broker.NewPaper(10000)
+			paper.Costs = broker.Costs{TakerFee: 0.001}
+			bot := openedBot(t, &Bot{Broker: paper, Sizer: fixedUnits(100), Ladder: []float32{0.5, 0.25, 0.25}},
+				strategy.Signal{Side: strategy.Long, Price: 100, StopLoss: 90, TakeProfit: 110, TakeProfits: []float32{110, 120, 130}})
+			entryFee := bot.currentPosition.EntryFee
+
+			for _, candle := range test.candles {
+				bot.Predict(context.Background(), candle)
+			}
+
+			if len(bot.Trades) != len(test.exits) {
+				t.Fatalf("expected %v trades, got %+v", len(test.exits), bot.Trades)
+			}
+			var sharedFee, netPL float32
+			for i, trade := range bot.Trades {
+				expected := test.exits[i]
+				if trade.ExitReason != expected.reason || trade.Units != expected.units || trade.ExitPrice != expected.price || trade.TakeProfit != expected.takeProfit {
+					t.Fatalf("trade %v: expected %+v, got %+v", i, expected, trade)
+				}
+				sharedFee += trade.EntryFee
+				netPL += trade.NetPL
+			}
+
+			//The entry fee is shared by the exits in proportion to their units
+			if closed := 100 - test.left; !almostEqual(sharedFee, entryFee*closed/100) {
+				t.Fatalf("expected an entry fee of %v shared by the trades, got %v", entryFee*closed/100, sharedFee)
+			}
+
+			if test.left == 0 {
+				if bot.currentPosition.Position != strategy.Neutral || len(paper.Positions()) != 0 {
+					t.Fatalf("expected no position, got %+v", bot.currentPosition)
+				}
+				if !almostEqual(paper.Balance(), 10000+netPL) {
+					t.Fatalf("expected balance %v, got %v", 10000+netPL, paper.Balance())
+				}
+				return
+			}
+
+			position := bot.currentPosition
+			if position.Units != test.left || paper.Positions()[0].Units != test.left {
+				t.Fatalf("expected %v units left, got %v in the bot and %+v in the broker", test.left, position.Units, paper.Positions())
+			}
+			if !almostEqual(position.RealisedPL, netPL) {
+				t.Fatalf("expected a realised P/L of %v, got %v", netPL, position.RealisedPL)
+			}
+			if next := position.Tranches[position.nextTranche()]; position.TakeProfit != next.TakeProfit {
+				t.Fatalf("expected the take profit of the next tranche %v, got %v", next.TakeProfit, position.TakeProfit)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"math"
+
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/sizing"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+const (
+	volatilityWindow = 20                   //Candles used to compute the volatility
+	recentCandles    = volatilityWindow + 1 //Candles kept by the bot, enough for the volatility and the average true range
+)
+
+// Retrieve the units of the position opened by the signal
+// The units come from the sizer and are capped by the max exposure
+func (bot *Bot) positionUnits(signal strategy.Signal) float32 {
+	capital := bot.capital()
+
+	var sizer sizing.Sizer = sizing.AllIn{}
+	if bot.Sizer != nil {
+		sizer = bot.Sizer
+	}
+
+	pastPL := make([]float32, 0, len(bot.Trades))
+	for _, trade := range bot.Trades {
+		pastPL = append(pastPL, trade.NetPL)
+	}
+
+	units := sizer.Units(sizing.Request{
+		Equity:     capital,
+		Price:      signal.Price,
+		StopLoss:   signal.StopLoss,
+		Volatility: bot.volatility(),
+		PastPL:     pastPL,
+	})
+
+	maxExposure := bot.MaxExposure
+	if maxExposure <= 0 {
+		maxExposure = 1
+	}
+	if maxUnits := maxExposure * capital / signal.Price; units > maxUnits {
+		units = maxUnits
+	}
+
+	return units
+}
+
+// Store the candle keeping only the last candles needed by the volatility and the average true range
+func (bot *Bot) trackCandle(candle data.Candle) {
+	bot.recentCandles = append(bot.recentCandles, candle)
+	if len(bot.recentCandles) > recentCandles {
+		bot.recentCandles = bot.recentCandles[1:]
+	}
+}
+
+// Retrieve the standard deviation of the returns of the recent closes
+func (bot *Bot) volatility() float32 {
+	candles := bot.recentCandles
+	if len(candles) > volatilityWindow+1 {
+		candles = candles[len(candles)-volatilityWindow-1:]
+	}
+	if len(candles) < 3 {
+		return 0
+	}
+
+	returns := make([]float64, 0, len(candles)-1)
+	var mean float64
+	for i := 1; i < len(candles); i++ {
+		r := float64(candles[i].Close/candles[i-1].Close) - 1
+		returns = append(returns, r)
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	return float32(math.Sqrt(variance))
+}
@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/sizing"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+func TestPositionUnitsExposureCap(t *testing.T) {
+	signal := strategy.Signal{Side: strategy.Long, Price: 100, StopLoss: 99}
+
+	tests := []struct {
+		name        string
+		sizer       sizing.Sizer
+		maxExposure float32
+		allocation  float32
+		units       float32
+	}{
+		{"default all in", nil, 0, 1, 100},
+		{"under the cap", sizing.FixedNotional{Notional: 5000}, 0, 1, 50},
+		//Risking 5% with a stop 1% away is a notional of 5 times the capital
+		{"capped at the capital", sizing.FixedFraction{Risk: 0.05}, 0, 1, 100},
+		{"capped at the leverage", sizing.FixedFraction{Risk: 0.05}, 3, 1, 300},
+		{"leverage over the size", sizing.FixedFraction{Risk: 0.05}, 10, 1, 500},
+		{"capped at the allocation", sizing.FixedFraction{Risk: 0.05}, 0, 0.25, 25},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot := &Bot{Broker: broker.NewPaper(10000), Allocation: test.allocation, Sizer: test.sizer, MaxExposure: test.maxExposure}
+			if units := bot.positionUnits(signal); !almostEqual(units, test.units) {
+				t.Fatalf("expected %v units, got %v", test.units, units)
+			}
+		})
+	}
+}
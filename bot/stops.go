@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+const atrPeriod = 14 //Candles used to compute the average true range
+
+// What a trailing stop can look at besides the candle
+// ATR is the average true range of the recent candles
+// KeyLevels are the sorted key levels of the strategy, if it has any
+type StopContext struct {
+	ATR       float32
+	KeyLevels []float32
+}
+
+// A trailing stop proposes a new stopLoss for a position of the given side after a candle
+// The bot only accepts stopLosses that move in the direction of the position
+type TrailingStop interface {
+	StopLoss(side int8, candle data.Candle, context StopContext) (float32, bool)
+}
+
+// Keep the stopLoss at a fixed Distance from the best price reached
+type FixedTrail struct {
+	Distance float32
+}
+
+func (trail FixedTrail) StopLoss(side int8, candle data.Candle, context StopContext) (float32, bool) {
+	if side == strategy.Long {
+		return candle.High - trail.Distance, true
+	}
+	return candle.Low + trail.Distance, true
+}
+
+// Keep the stopLoss at Multiple times the average true range from the best price reached
+type ATRTrail struct {
+	Multiple float32
+}
+
+func (trail ATRTrail) StopLoss(side int8, candle data.Candle, context StopContext) (float32, bool) {
+	if context.ATR <= 0 {
+		return 0, false
+	}
+	return FixedTrail{Distance: trail.Multiple * context.ATR}.StopLoss(side, candle, context)
+}
+
+// Move the stopLoss to the closest key level the price has left behind
+// For a long position it is the highest key level under the close, for a short one the lowest over the close
+type KeyLevelTrail struct{}
+
+func (KeyLevelTrail) StopLoss(side int8, candle data.Candle, context StopContext) (float32, bool) {
+	if side == strategy.Long {
+		for i := len(context.KeyLevels) - 1; i >= 0; i-- {
+			if context.KeyLevels[i] < candle.Close {
+				return context.KeyLevels[i], true
+			}
+		}
+	} else {
+		for i := 0; i < len(context.KeyLevels); i++ {
+			if context.KeyLevels[i] > candle.Close {
+				return context.KeyLevels[i], true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Parse a trailing stop in the form NAME[:PARAMETER]
+//
+//	fixed:DISTANCE  e.g. fixed:500 keeps the stopLoss 500 away from the best price
+//	atr:MULTIPLE    e.g. atr:3 keeps the stopLoss 3 average true ranges away from the best price
+//	key-levels      moves the stopLoss to the key levels left behind
+func ParseTrailingStop(value string) (TrailingStop, error) {
+	name, parameter, hasParameter := strings.Cut(value, ":")
+	if name == "key-levels" {
+		return KeyLevelTrail{}, nil
+	}
+
+	if !hasParameter {
+		return nil, fmt.Errorf("TRAILING STOP %q NEEDS A PARAMETER, USE %v:VALUE", name, name)
+	}
+	parsed, err := strconv.ParseFloat(parameter, 32)
+	if err != nil || parsed <= 0 {
+		return nil, fmt.Errorf("INVALID PARAMETER %q FOR TRAILING STOP %v, IT MUST BE A POSITIVE NUMBER", parameter, name)
+	}
+
+	switch name {
+	case "fixed":
+		return FixedTrail{Distance: float32(parsed)}, nil
+	case "atr":
+		return ATRTrail{Multiple: float32(parsed)}, nil
+	}
+	return nil, fmt.Errorf("UNKNOWN TRAILING STOP %q, USE fixed, atr OR key-levels", name)
+}
+
+// Move the stopLoss of the opened position after the candle
+// It moves to the entry price once the profit reaches the BreakEven fraction of the entry price,
+// then it follows the trailing stop
+// The stopLoss never moves against the position, so the new level applies from the next candle
+func (bot *Bot) updateStopLoss(candle data.Candle) {
+	position := &bot.currentPosition
+	if position.Position == strategy.Neutral {
+		return
+	}
+	side := float32(position.Position)
+
+	candidates := []float32{}
+	if bot.BreakEven > 0 && side*(candle.Close-position.BuyPrice) >= bot.BreakEven*position.BuyPrice {
+		candidates = append(candidates, position.BuyPrice)
+	}
+	if bot.Trailing != nil {
+		context := StopContext{ATR: bot.averageTrueRange()}
+		if levels, ok := bot.Strategy.(interface{ KeyLevels() []float32 }); ok {
+			context.KeyLevels = levels.KeyLevels()
+		}
+		if stopLoss, ok := bot.Trailing.StopLoss(position.Position, candle, context); ok {
+			candidates = append(candidates, stopLoss)
+		}
+	}
+
+	for _, stopLoss := range candidates {
+		//Only tighter stopLosses that are still on the right side of the price
+		if side*(stopLoss-position.StopLoss) > 0 && side*(candle.Close-stopLoss) > 0 {
+			bot.log().PrintStatus("STOP LOSS MOVED", fmt.Sprintf("From %f to %f", position.StopLoss, stopLoss))
+			position.StopLoss = stopLoss
+		}
+	}
+}
+
+// Retrieve the average true range of the recent candles
+func (bot *Bot) averageTrueRange() float32 {
+	candles := bot.recentCandles
+	if len(candles) > atrPeriod+1 {
+		candles = candles[len(candles)-atrPeriod-1:]
+	}
+	if len(candles) < 2 {
+		return 0
+	}
+
+	var total float32
+	for i := 1; i < len(candles); i++ {
+		trueRange := candles[i].High - candles[i].Low
+		if gap := utils.AbsDifference(candles[i].High, candles[i-1].Close); gap > trueRange {
+			trueRange = gap
+		}
+		if gap := utils.AbsDifference(candles[i].Low, candles[i-1].Close); gap > trueRange {
+			trueRange = gap
+		}
+		total += trueRange
+	}
+
+	return total / float32(len(candles)-1)
+}
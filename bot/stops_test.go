@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+// An idle strategy with key levels
+type levelsStrategy struct {
+	idleStrategy
+	levels []float32
+}
+
+func (strategy levelsStrategy) KeyLevels() []float32 {
+	return strategy.levels
+}
+
+// Candles with a range of 2 and no gaps, so their average true range is 2
+func flatCandles(n int) []data.Candle {
+	candles := make([]data.Candle, n)
+	for i := range candles {
+		candles[i] = data.Candle{Open: 100, High: 101, Low: 99, Close: 100}
+	}
+	return candles
+}
+
+func TestUpdateStopLoss(t *testing.T) {
+	long := strategy.Signal{Side: strategy.Long, Price: 100, StopLoss: 90, TakeProfit: 150}
+	short := strategy.Signal{Side: strategy.Short, Price: 100, StopLoss: 110, TakeProfit: 50}
+
+	tests := []struct {
+		name     string
+		bot      Bot
+		signal   strategy.Signal
+		stopLoss float32 //The stopLoss before the candle, 0 keeps the one of the signal
+		history  []data.Candle
+		candle   data.Candle
+		expected float32
+	}{
+		{"fixed", Bot{Trailing: FixedTrail{Distance: 5}}, long, 0, nil, data.Candle{Open: 100, High: 110, Low: 99, Close: 108}, 105},
+		{"fixed never loosens", Bot{Trailing: FixedTrail{Distance: 5}}, long, 105, nil, data.Candle{Open: 104, High: 106, Low: 103, Close: 106}, 105},
+		{"fixed over the close", Bot{Trailing: FixedTrail{Distance: 5}}, long, 0, nil, data.Candle{Open: 100, High: 120, Low: 99, Close: 100}, 90},
+		{"fixed short", Bot{Trailing: FixedTrail{Distance: 5}}, short, 0, nil, data.Candle{Open: 100, High: 101, Low: 90, Close: 92}, 95},
+		{"atr", Bot{Trailing: ATRTrail{Multiple: 2}}, long, 0, flatCandles(5), data.Candle{Open: 100, High: 101, Low: 99, Close: 100}, 97},
+		{"atr without history", Bot{Trailing: ATRTrail{Multiple: 2}}, long, 0, nil, data.Candle{Open: 100, High: 101, Low: 99, Close: 100}, 90},
+		{"key levels", Bot{Trailing: KeyLevelTrail{}, Strategy: levelsStrategy{levels: []float32{95, 102, 110}}}, long, 0, nil, data.Candle{Open: 100, High: 106, Low: 99, Close: 105}, 102},
+		{"key levels short", Bot{Trailing: KeyLevelTrail{}, Strategy: levelsStrategy{levels: []float32{95, 102, 110}}}, short, 0, nil, data.Candle{Open: 100, High: 101, Low: 96, Close: 97}, 102},
+		{"key levels none left behind", Bot{Trailing: KeyLevelTrail{}, Strategy: levelsStrategy{levels: []float32{110}}}, long, 0, nil, data.Candle{Open: 100, High: 106, Low: 99, Close: 105}, 90},
+		{"break even", Bot{BreakEven: 0.05}, long, 0, nil, data.Candle{Open: 100, High: 107, Low: 99, Close: 106}, 100},
+		{"break even not reached", Bot{BreakEven: 0.05}, long, 0, nil, data.Candle{Open: 100, High: 107, Low: 99, Close: 104}, 90},
+		{"break even short", Bot{BreakEven: 0.05}, short, 0, nil, data.Candle{Open: 100, High: 101, Low: 93, Close: 94}, 100},
+		{"break even then the tighter trail", Bot{BreakEven: 0.05, Trailing: FixedTrail{Distance: 3}}, long, 0, nil, data.Candle{Open: 100, High: 110, Low: 99, Close: 109}, 107},
+		{"break even over the looser trail", Bot{BreakEven: 0.05, Trailing: FixedTrail{Distance: 8}}, long, 0, nil, data.Candle{Open: 100, High: 107, Low: 99, Close: 106}, 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot := test.bot
+			openedBot(t, &bot, test.signal)
+			if test.stopLoss != 0 {
+				bot.currentPosition.StopLoss = test.stopLoss
+			}
+			bot.recentCandles = test.history
+
+			bot.trackCandle(test.candle)
+			bot.updateStopLoss(test.candle)
+			if bot.currentPosition.StopLoss != test.expected {
+				t.Fatalf("expected the stop loss at %v, got %v", test.expected, bot.currentPosition.StopLoss)
+			}
+			if bot.currentPosition.InitialStopLoss != test.signal.StopLoss {
+				t.Fatalf("the initial stop loss moved to %v", bot.currentPosition.InitialStopLoss)
+			}
+		})
+	}
+}
+
+func TestParseTrailingStop(t *testing.T) {
+	tests := []struct {
+		value    string
+		trailing TrailingStop
+		fails    bool
+	}{
+		{"fixed:500", FixedTrail{Distance: 500}, false},
+		{"atr:3", ATRTrail{Multiple: 3}, false},
+		{"key-levels", KeyLevelTrail{}, false},
+		{"fixed", nil, true},
+		{"atr:0", nil, true},
+		{"percent:1", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			trailing, err := ParseTrailingStop(test.value)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", trailing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trailing != test.trailing {
+				t.Fatalf("expected %+v, got %+v", test.trailing, trailing)
+			}
+		})
+	}
+}
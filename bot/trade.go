@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/data"
+)
+
+// The record of a closed position, or of the closed part of a position that scales out
+// GrossPL is the profit/loss of the prices (slippage included), the costs are reported separately
+// NetPL is GrossPL - EntryFee - ExitFee - Funding
+// InitialStopLoss is the one of the signal, StopLoss and TakeProfit are the levels at the exit
+// Area is the interest area that triggered the entry
+type Trade struct {
+	Symbol                        string
+	Side                          int8
+	EntryTimestamp, ExitTimestamp int64
+	EntryPrice, ExitPrice, Units  float32
+	InitialStopLoss, StopLoss     float32
+	TakeProfit                    float32
+	ExitReason                    string
+	EntryFee, ExitFee             float32
+	Slippage, Funding             float32
+	GrossPL, NetPL                float32
+	Area                          data.Candle
+}
+
+// Build the trade record of the units of the position closed by the exit fill
+// The entry costs are shared among the exits in proportion to the units
+func newTrade(symbol string, position Position, exit broker.Fill, reason string) Trade {
+	share := float32(1)
+	if position.InitialUnits > 0 {
+		share = exit.Units / position.InitialUnits
+	}
+
+	trade := Trade{
+		Symbol:          symbol,
+		Side:            position.Position,
+		EntryTimestamp:  position.EntryTimestamp,
+		ExitTimestamp:   exit.Timestamp,
+		EntryPrice:      position.BuyPrice,
+		ExitPrice:       exit.Price,
+		Units:           exit.Units,
+		InitialStopLoss: position.InitialStopLoss,
+		StopLoss:        position.StopLoss,
+		TakeProfit:      position.TakeProfit,
+		ExitReason:      reason,
+		EntryFee:        position.EntryFee * share,
+		ExitFee:         exit.Fee,
+		Slippage:        position.EntrySlippage*share + exit.Slippage,
+		Funding:         exit.Funding,
+		GrossPL:         exit.PL,
+		Area:            position.Area,
+	}
+	trade.NetPL = trade.GrossPL - trade.EntryFee - trade.ExitFee - trade.Funding
+
+	return trade
+}
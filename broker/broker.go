@@ -0,0 +1,82 @@
+package broker
+
+import "github.com/frappaf/tradingBot/data"
+
+// Types of order
+// A Market order is filled immediately at its Price (the last known price)
+// A Limit order is filled when the price reaches its Price or better
+// A Stop order is filled when the price reaches its Price or worse
+type OrderType int8
+
+const (
+	Market OrderType = iota
+	Limit
+	Stop
+)
+
+// An order to buy (Side 1, long) or sell (Side -1, short) Units of the Symbol
+// The ID is assigned by the broker when the order is placed
+// A Resting market order executes an order that was resting on the book at its Price and was reached by the price
+// (e.g. a take profit): it is filled at once like a market order but as a limit one, paying the maker fee without slippage
+type Order struct {
+	ID        int
+	Symbol    string
+	Side      int8
+	Type      OrderType
+	Resting   bool
+	Units     float32
+	Price     float32
+	Timestamp int64
+}
+
+// Check if the order provides liquidity, so it pays the maker fee and has no slippage
+func (order Order) maker() bool {
+	return order.Type == Limit || order.Resting
+}
+
+// The execution of an order
+// The Price includes the Slippage, that is the cost of the slippage (units * price difference)
+// PL is the gross profit/loss realised by the fill, it is 0 when the fill opens or increases a position
+// Fee and Funding are the costs paid by the fill, the net profit/loss is PL - Fee - Funding
+type Fill struct {
+	OrderID   int
+	Symbol    string
+	Side      int8
+	Units     float32
+	Price     float32
+	PL        float32
+	Fee       float32
+	Slippage  float32
+	Funding   float32
+	Timestamp int64
+}
+
+// Retrieve the profit/loss of the fill after the costs
+func (fill Fill) NetPL() float32 {
+	return fill.PL - fill.Fee - fill.Funding
+}
+
+// The position held on a symbol, Side is 1 (long) or -1 (short)
+// OpenTimestamp is the time of the first fill, used to compute the funding
+type Position struct {
+	Symbol        string
+	Side          int8
+	Units         float32
+	EntryPrice    float32
+	OpenTimestamp int64
+}
+
+// A broker executes the orders and holds the positions and the balance
+// PlaceOrder retrieves the fill of a market order, pending orders retrieve an empty fill with the assigned OrderID
+type Broker interface {
+	PlaceOrder(order Order) (Fill, error)
+	Cancel(orderID int) error
+	Positions() []Position
+	Balance() float32
+}
+
+// A simulated broker needs the candles to fill the pending orders
+// OnCandle retrieves the fills of the orders triggered by the candle
+type Simulator interface {
+	OnCandle(symbol string, candle data.Candle) []Fill
+}
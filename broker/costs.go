@@ -0,0 +1,43 @@
+package broker
+
+const secondsPerDay = 24 * 60 * 60
+
+// The trading costs applied by the paper broker
+// MakerFee and TakerFee are fractions of the notional (units * price) paid on every fill,
+// limit (and resting) orders pay the maker fee, market and stop orders the taker fee
+// FixedSlippage is a fraction of the price and VolatilitySlippage a fraction of the range (High - Low) of the last candle,
+// both move the fill price of market and stop orders against the order
+// ShortFunding is the daily fraction of the notional paid while holding a short position
+type Costs struct {
+	MakerFee, TakerFee                float32
+	FixedSlippage, VolatilitySlippage float32
+	ShortFunding                      float32
+}
+
+// Retrieve the fee of a fill of the order
+func (costs Costs) fee(order Order, units, price float32) float32 {
+	if order.maker() {
+		return costs.MakerFee * units * price
+	}
+	return costs.TakerFee * units * price
+}
+
+// Retrieve the price moved against the order side by the slippage
+// lastRange is the range of the last candle of the symbol
+func (costs Costs) slip(order Order, price, lastRange float32) float32 {
+	if order.maker() {
+		return price
+	}
+
+	slippage := costs.FixedSlippage*price + costs.VolatilitySlippage*lastRange
+	return price + float32(order.Side)*slippage
+}
+
+// Retrieve the funding paid by a short position of the given notional held from openTimestamp to closeTimestamp
+func (costs Costs) funding(notional float32, openTimestamp, closeTimestamp int64) float32 {
+	if closeTimestamp <= openTimestamp {
+		return 0
+	}
+	days := float32(closeTimestamp-openTimestamp) / secondsPerDay
+	return costs.ShortFunding * notional * days
+}
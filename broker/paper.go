@@ -0,0 +1,206 @@
+package broker
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// Units left by the rounding errors of partial closes, they are ignored
+const dustUnits float32 = 1e-7
+
+// The paper broker simulates the executions without real money
+// The balance changes when a position is reduced or closed, adding the realised profit/loss,
+// and on every fill, subtracting the fees and the funding
+// The Costs must be set before placing the first order
+// It is safe for concurrent use, so bots running in different goroutines can share it
+type Paper struct {
+	Costs Costs
+
+	mu         sync.Mutex
+	balance    float32
+	positions  map[string]*Position
+	pending    map[int]Order
+	nextID     int
+	lastRanges map[string]float32
+}
+
+func NewPaper(balance float32) *Paper {
+	return &Paper{
+		balance:    balance,
+		positions:  make(map[string]*Position),
+		pending:    make(map[int]Order),
+		lastRanges: make(map[string]float32),
+	}
+}
+
+// Fill a market order immediately or store a limit/stop order until a candle triggers it
+func (paper *Paper) PlaceOrder(order Order) (Fill, error) {
+	if order.Units <= 0 {
+		return Fill{}, fmt.Errorf("ORDER UNITS MUST BE POSITIVE")
+	}
+	if order.Side != 1 && order.Side != -1 {
+		return Fill{}, fmt.Errorf("ORDER SIDE MUST BE LONG OR SHORT")
+	}
+	if order.Price <= 0 {
+		return Fill{}, fmt.Errorf("ORDER PRICE MUST BE POSITIVE")
+	}
+
+	paper.mu.Lock()
+	defer paper.mu.Unlock()
+
+	paper.nextID++
+	order.ID = paper.nextID
+
+	if order.Type != Market {
+		paper.pending[order.ID] = order
+		return Fill{OrderID: order.ID}, nil
+	}
+
+	return paper.fill(order, paper.Costs.slip(order, order.Price, paper.lastRanges[order.Symbol])), nil
+}
+
+// Remove a pending order
+func (paper *Paper) Cancel(orderID int) error {
+	paper.mu.Lock()
+	defer paper.mu.Unlock()
+
+	if _, ok := paper.pending[orderID]; !ok {
+		return fmt.Errorf("ORDER %v NOT FOUND", orderID)
+	}
+	delete(paper.pending, orderID)
+	return nil
+}
+
+// Retrieve the opened positions sorted by symbol
+func (paper *Paper) Positions() []Position {
+	paper.mu.Lock()
+	defer paper.mu.Unlock()
+
+	positions := make([]Position, 0, len(paper.positions))
+	for _, position := range paper.positions {
+		positions = append(positions, *position)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	return positions
+}
+
+func (paper *Paper) Balance() float32 {
+	paper.mu.Lock()
+	defer paper.mu.Unlock()
+	return paper.balance
+}
+
+// Fill the pending orders of the symbol triggered by the candle
+// Limit orders are filled at their price, stop orders at their price or at the open if the candle gapped over it
+// The range of the candle is stored to compute the slippage of the next orders
+func (paper *Paper) OnCandle(symbol string, candle data.Candle) []Fill {
+	paper.mu.Lock()
+	defer paper.mu.Unlock()
+
+	paper.lastRanges[symbol] = candle.High - candle.Low
+
+	ids := make([]int, 0, len(paper.pending))
+	for id := range paper.pending {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var fills []Fill
+	for _, id := range ids {
+		order := paper.pending[id]
+		if order.Symbol != symbol {
+			continue
+		}
+
+		price, triggered := triggerPrice(order, candle)
+		if !triggered {
+			continue
+		}
+
+		delete(paper.pending, id)
+		order.Timestamp = candle.Timestamp
+		fills = append(fills, paper.fill(order, paper.Costs.slip(order, price, paper.lastRanges[symbol])))
+	}
+
+	return fills
+}
+
+// Check if a pending order is triggered by the candle and retrieve the fill price
+func triggerPrice(order Order, candle data.Candle) (float32, bool) {
+	buy := order.Side == 1
+
+	switch order.Type {
+	case Limit:
+		if buy && candle.Low <= order.Price || !buy && candle.High >= order.Price {
+			if buy && candle.Open < order.Price || !buy && candle.Open > order.Price {
+				return candle.Open, true
+			}
+			return order.Price, true
+		}
+	case Stop:
+		if buy && candle.High >= order.Price || !buy && candle.Low <= order.Price {
+			if buy && candle.Open > order.Price || !buy && candle.Open < order.Price {
+				return candle.Open, true
+			}
+			return order.Price, true
+		}
+	}
+
+	return 0, false
+}
+
+// Apply an order to the position of its symbol at the given price (slippage included)
+// An order on the same side opens or increases the position averaging the entry price
+// An order on the opposite side reduces or closes the position realising the profit/loss and paying the funding of shorts,
+// the exceeding units open a position on the other side
+func (paper *Paper) fill(order Order, price float32) Fill {
+	fill := Fill{
+		OrderID:   order.ID,
+		Symbol:    order.Symbol,
+		Side:      order.Side,
+		Units:     order.Units,
+		Price:     price,
+		Fee:       paper.Costs.fee(order, order.Units, price),
+		Slippage:  order.Units * utils.AbsDifference(price, order.Price),
+		Timestamp: order.Timestamp,
+	}
+	paper.balance -= fill.Fee
+
+	units := order.Units
+	position, ok := paper.positions[order.Symbol]
+
+	if ok && position.Side != order.Side {
+		closed := units
+		if closed > position.Units {
+			closed = position.Units
+		}
+
+		fill.PL = float32(position.Side) * (price - position.EntryPrice) * closed
+		if position.Side == -1 {
+			fill.Funding = paper.Costs.funding(position.EntryPrice*closed, position.OpenTimestamp, order.Timestamp)
+		}
+		paper.balance += fill.PL - fill.Funding
+
+		position.Units -= closed
+		units -= closed
+		if position.Units <= dustUnits {
+			delete(paper.positions, order.Symbol)
+			ok = false
+		}
+	}
+
+	if units > dustUnits {
+		if ok {
+			position.EntryPrice = (position.EntryPrice*position.Units + price*units) / (position.Units + units)
+			position.Units += units
+		} else {
+			paper.positions[order.Symbol] = &Position{Symbol: order.Symbol, Side: order.Side, Units: units, EntryPrice: price, OpenTimestamp: order.Timestamp}
+		}
+	}
+
+	return fill
+}
@@ -0,0 +1,177 @@
+package broker
+
+import (
+	"math"
+	"testing"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+func almostEqual(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func TestTriggerPrice(t *testing.T) {
+	candle := data.Candle{Open: 100, High: 110, Low: 90, Close: 105}
+
+	tests := []struct {
+		name      string
+		order     Order
+		candle    data.Candle
+		price     float32
+		triggered bool
+	}{
+		{"buy limit reached", Order{Side: 1, Type: Limit, Price: 95}, candle, 95, true},
+		{"buy limit not reached", Order{Side: 1, Type: Limit, Price: 85}, candle, 0, false},
+		{"buy limit gap", Order{Side: 1, Type: Limit, Price: 120}, candle, 100, true},
+		{"sell limit reached", Order{Side: -1, Type: Limit, Price: 108}, candle, 108, true},
+		{"sell limit not reached", Order{Side: -1, Type: Limit, Price: 115}, candle, 0, false},
+		{"sell limit gap", Order{Side: -1, Type: Limit, Price: 80}, candle, 100, true},
+		{"buy stop reached", Order{Side: 1, Type: Stop, Price: 105}, candle, 105, true},
+		{"buy stop not reached", Order{Side: 1, Type: Stop, Price: 115}, candle, 0, false},
+		{"buy stop gap", Order{Side: 1, Type: Stop, Price: 95}, candle, 100, true},
+		{"sell stop reached", Order{Side: -1, Type: Stop, Price: 92}, candle, 92, true},
+		{"sell stop not reached", Order{Side: -1, Type: Stop, Price: 85}, candle, 0, false},
+		{"sell stop gap", Order{Side: -1, Type: Stop, Price: 105}, candle, 100, true},
+		{"market", Order{Side: 1, Type: Market, Price: 100}, candle, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			price, triggered := triggerPrice(test.order, test.candle)
+			if triggered != test.triggered || price != test.price {
+				t.Fatalf("expected %v %v, got %v %v", test.price, test.triggered, price, triggered)
+			}
+		})
+	}
+}
+
+func TestPaperFills(t *testing.T) {
+	costs := Costs{MakerFee: 0.001, TakerFee: 0.002, FixedSlippage: 0.01, VolatilitySlippage: 0.1, ShortFunding: 0.01}
+	candle := data.Candle{Open: 100, High: 110, Low: 90, Close: 105, Timestamp: 3600}
+
+	tests := []struct {
+		name     string
+		order    Order
+		price    float32
+		fee      float32
+		slippage float32
+	}{
+		//1% of 100 plus 10% of the range (20) of the last candle
+		{"market buy", Order{Side: 1, Type: Market, Units: 2, Price: 100}, 103, 2 * 103 * 0.002, 6},
+		{"market sell", Order{Side: -1, Type: Market, Units: 2, Price: 100}, 97, 2 * 97 * 0.002, 6},
+		{"resting market", Order{Side: -1, Type: Market, Resting: true, Units: 2, Price: 100}, 100, 2 * 100 * 0.001, 0},
+		{"limit", Order{Side: 1, Type: Limit, Units: 2, Price: 95}, 95, 2 * 95 * 0.001, 0},
+		//Slipped from the open of the gap, 1% of 100 plus 10% of 20
+		{"stop gap", Order{Side: 1, Type: Stop, Units: 2, Price: 95}, 103, 2 * 103 * 0.002, 2 * 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paper := NewPaper(1000)
+			paper.Costs = costs
+			paper.OnCandle("BTC", candle)
+			test.order.Symbol = "BTC"
+
+			fill, err := paper.PlaceOrder(test.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.order.Type != Market {
+				fills := paper.OnCandle("BTC", candle)
+				if len(fills) != 1 {
+					t.Fatalf("expected 1 fill, got %v", len(fills))
+				}
+				fill = fills[0]
+			}
+
+			if !almostEqual(fill.Price, test.price) || !almostEqual(fill.Fee, test.fee) || !almostEqual(fill.Slippage, test.slippage) {
+				t.Fatalf("expected price %v fee %v slippage %v, got %v %v %v", test.price, test.fee, test.slippage, fill.Price, fill.Fee, fill.Slippage)
+			}
+			if !almostEqual(paper.Balance(), 1000-test.fee) {
+				t.Fatalf("expected balance %v, got %v", 1000-test.fee, paper.Balance())
+			}
+			positions := paper.Positions()
+			if len(positions) != 1 || positions[0].Side != test.order.Side || positions[0].Units != test.order.Units || positions[0].EntryPrice != fill.Price {
+				t.Fatalf("expected a position of %v units at %v, got %+v", test.order.Units, fill.Price, positions)
+			}
+		})
+	}
+}
+
+func TestPaperPositionAccounting(t *testing.T) {
+	const day = 24 * 60 * 60
+
+	tests := []struct {
+		name     string
+		orders   []Order
+		pl       float32
+		funding  float32
+		balance  float32
+		position *Position
+	}{
+		{
+			"increase averages the entry",
+			[]Order{{Side: 1, Units: 1, Price: 100}, {Side: 1, Units: 3, Price: 120}},
+			0, 0, 1000,
+			&Position{Symbol: "BTC", Side: 1, Units: 4, EntryPrice: 115},
+		},
+		{
+			"partial close",
+			[]Order{{Side: 1, Units: 4, Price: 100}, {Side: -1, Units: 1, Price: 110}},
+			10, 0, 1010,
+			&Position{Symbol: "BTC", Side: 1, Units: 3, EntryPrice: 100},
+		},
+		{
+			"close",
+			[]Order{{Side: 1, Units: 2, Price: 100}, {Side: -1, Units: 2, Price: 90}},
+			-20, 0, 980,
+			nil,
+		},
+		{
+			"reverse",
+			[]Order{{Side: 1, Units: 1, Price: 100}, {Side: -1, Units: 3, Price: 110}},
+			10, 0, 1010,
+			&Position{Symbol: "BTC", Side: -1, Units: 2, EntryPrice: 110},
+		},
+		{
+			"short funding",
+			[]Order{{Side: -1, Units: 2, Price: 100}, {Side: 1, Units: 2, Price: 90, Timestamp: 2 * day}},
+			20, 4, 1016,
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paper := NewPaper(1000)
+			paper.Costs = Costs{ShortFunding: 0.01}
+
+			var fill Fill
+			for _, order := range test.orders {
+				order.Symbol = "BTC"
+				var err error
+				if fill, err = paper.PlaceOrder(order); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !almostEqual(fill.PL, test.pl) || !almostEqual(fill.Funding, test.funding) {
+				t.Fatalf("expected pl %v funding %v, got %v %v", test.pl, test.funding, fill.PL, fill.Funding)
+			}
+			if !almostEqual(paper.Balance(), test.balance) {
+				t.Fatalf("expected balance %v, got %v", test.balance, paper.Balance())
+			}
+			positions := paper.Positions()
+			if test.position == nil {
+				if len(positions) != 0 {
+					t.Fatalf("expected no position, got %+v", positions)
+				}
+				return
+			}
+			if len(positions) != 1 || positions[0] != *test.position {
+				t.Fatalf("expected %+v, got %+v", *test.position, positions)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/frappaf/tradingBot/config"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+// A value of the config file and the flag receiving it
+// Field is its path in the file (e.g. risk.costs.takerFee)
+type configValue struct {
+	field string
+	flag  string
+	value string
+}
+
+// Retrieve the values of the config file as command line flags
+// The missing and zero fields are left out so that they keep the defaults of the flags
+func configValues(settings config.Config) []configValue {
+	var values []configValue
+	text := func(field, flag, value string) {
+		if value != "" {
+			values = append(values, configValue{field, flag, value})
+		}
+	}
+	number := func(field, flag string, value float32) {
+		if value != 0 {
+			values = append(values, configValue{field, flag, formatFloat(value)})
+		}
+	}
+	integer := func(field, flag string, value int64) {
+		if value != 0 {
+			values = append(values, configValue{field, flag, strconv.FormatInt(value, 10)})
+		}
+	}
+
+	text("data.file", "data-file", settings.Data.File)
+	if settings.Data.CacheDir != nil {
+		values = append(values, configValue{"data.cacheDir", "cache-dir", *settings.Data.CacheDir})
+	}
+	text("data.apiUrl", "api-url", settings.Data.APIURL)
+	text("data.channel", "channel", settings.Data.Channel)
+	text("data.ablyKey", "ably-key", settings.Data.AblyKey)
+
+	text("symbol", "symbol", settings.Symbol)
+	if len(settings.Portfolio) > 0 {
+		var pairs []string
+		for symbol, allocation := range settings.Portfolio {
+			pairs = append(pairs, symbol+"="+formatFloat(allocation))
+		}
+		sort.Strings(pairs)
+		values = append(values, configValue{"portfolio", "portfolio", strings.Join(pairs, ",")})
+	}
+	text("from", "from", settings.From)
+	text("start", "start", settings.Start)
+	text("end", "end", settings.End)
+	text("resolution", "resolution", settings.Resolution)
+	number("initialBalance", "balance", settings.InitialBalance)
+
+	if settings.Strategy != (config.Strategy{}) {
+		params := settings.Strategy.Params()
+		var pairs []string
+		for _, name := range strategy.ParamNames {
+			value, _ := params.Value(name)
+			pairs = append(pairs, name+"="+formatFloat(value))
+		}
+		values = append(values, configValue{"strategy", "params", strings.Join(pairs, ",")})
+	}
+
+	risk := settings.Risk
+	text("risk.sizer", "sizer", risk.Sizer)
+	number("risk.maxExposure", "max-exposure", risk.MaxExposure)
+	text("risk.bothHit", "both-hit", risk.BothHit)
+	text("risk.trailing", "trailing", risk.Trailing)
+	number("risk.breakEven", "break-even", risk.BreakEven)
+	if len(risk.Ladder) > 0 {
+		fractions := make([]string, len(risk.Ladder))
+		for i, fraction := range risk.Ladder {
+			fractions[i] = formatFloat(fraction)
+		}
+		values = append(values, configValue{"risk.ladder", "ladder", strings.Join(fractions, ",")})
+	}
+	number("risk.costs.makerFee", "maker-fee", risk.Costs.MakerFee)
+	number("risk.costs.takerFee", "taker-fee", risk.Costs.TakerFee)
+	number("risk.costs.slippage", "slippage", risk.Costs.Slippage)
+	number("risk.costs.volatilitySlippage", "volatility-slippage", risk.Costs.VolatilitySlippage)
+	number("risk.costs.shortFunding", "short-funding", risk.Costs.ShortFunding)
+
+	integer("backtest.benchmarkRuns", "benchmark-runs", int64(settings.Backtest.BenchmarkRuns))
+	if settings.Backtest.Seed != nil {
+		values = append(values, configValue{"backtest.seed", "seed", strconv.FormatInt(*settings.Backtest.Seed, 10)})
+	}
+	integer("backtest.monteCarlo.runs", "monte-carlo", int64(settings.Backtest.MonteCarlo.Runs))
+	text("backtest.monteCarlo.resampling", "resampling", settings.Backtest.MonteCarlo.Resampling)
+	number("backtest.monteCarlo.ruin", "ruin", settings.Backtest.MonteCarlo.Ruin)
+
+	text("optimisation.grid", "grid", settings.Optimisation.Grid)
+	text("optimisation.objective", "objective", settings.Optimisation.Objective)
+	integer("optimisation.workers", "workers", int64(settings.Optimisation.Workers))
+	integer("optimisation.inSample", "in-sample", int64(settings.Optimisation.InSample))
+	integer("optimisation.outOfSample", "out-of-sample", int64(settings.Optimisation.OutOfSample))
+
+	text("outputs.journal", "journal", strings.Join(settings.Outputs.Journal, ","))
+	text("outputs.report", "report", settings.Outputs.Report)
+	text("outputs.equity", "equity", settings.Outputs.Equity)
+	text("outputs.monteCarlo", "monte-carlo-out", settings.Outputs.MonteCarlo)
+	text("outputs.sweep", "out", settings.Outputs.Sweep)
+	text("outputs.heatmap", "heatmap", settings.Outputs.Heatmap)
+	return values
+}
+
+func formatFloat(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
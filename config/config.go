@@ -0,0 +1,424 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/backtest"
+	"github.com/frappaf/tradingBot/bot"
+	"github.com/frappaf/tradingBot/sizing"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+// The layout of the dates of the file
+const DateLayout = "2006-01-02"
+
+// The settings of the bot and of the backtests read from a JSON file
+// Every field is optional: the zero fields keep the defaults of the command line and the flags passed explicitly win over the file
+// From is the start of the daily history, Start the start of the replay (or of the first in-sample window), End its end
+type Config struct {
+	Data           Data               `json:"data"`
+	Symbol         string             `json:"symbol"`
+	Portfolio      map[string]float32 `json:"portfolio"`
+	From           string             `json:"from"`
+	Start          string             `json:"start"`
+	End            string             `json:"end"`
+	Resolution     string             `json:"resolution"`
+	InitialBalance float32            `json:"initialBalance"`
+	Strategy       Strategy           `json:"strategy"`
+	Risk           Risk               `json:"risk"`
+	Backtest       Backtest           `json:"backtest"`
+	Optimisation   Optimisation       `json:"optimisation"`
+	Outputs        Outputs            `json:"outputs"`
+}
+
+// Where the candles and the live price come from
+type Data struct {
+	File     string  `json:"file"`
+	CacheDir *string `json:"cacheDir"` //Empty disables the cache, so it is a pointer to tell it apart from a missing field
+	APIURL   string  `json:"apiUrl"`
+	Channel  string  `json:"channel"`
+	AblyKey  string  `json:"ablyKey"`
+}
+
+// The parameters of the breakout strategy (see strategy.Params)
+// They are pointers to tell a zero value apart from a missing field, the missing fields take the default values
+type Strategy struct {
+	MinDifference *float32 `json:"minDifference"`
+	StopLoss      *float32 `json:"stopLoss"`
+	TakeProfit    *float32 `json:"takeProfit"`
+	LevelDistance *float32 `json:"levelDistance"`
+	RewardMargin  *float32 `json:"rewardMargin"`
+	MinRange      *float32 `json:"minRange"`
+	MaxRange      *float32 `json:"maxRange"`
+	BufferLength  *int     `json:"bufferLength"`
+}
+
+// The sizing, the exits and the costs of the positions, in the formats of the flags
+type Risk struct {
+	Sizer       string    `json:"sizer"`
+	MaxExposure float32   `json:"maxExposure"`
+	BothHit     string    `json:"bothHit"`
+	Trailing    string    `json:"trailing"`
+	BreakEven   float32   `json:"breakEven"`
+	Ladder      []float32 `json:"ladder"`
+	Costs       Costs     `json:"costs"`
+}
+
+// The fees, the slippage and the funding as fractions (see broker.Costs)
+type Costs struct {
+	MakerFee           float32 `json:"makerFee"`
+	TakerFee           float32 `json:"takerFee"`
+	Slippage           float32 `json:"slippage"`
+	VolatilitySlippage float32 `json:"volatilitySlippage"`
+	ShortFunding       float32 `json:"shortFunding"`
+}
+
+// The analysis of a backtest
+type Backtest struct {
+	BenchmarkRuns int        `json:"benchmarkRuns"`
+	Seed          *int64     `json:"seed"` //0 is a valid seed, so it is a pointer to tell it apart from a missing field
+	MonteCarlo    MonteCarlo `json:"monteCarlo"`
+}
+
+// The Monte Carlo resampling of the trades (see backtest.MonteCarlo)
+type MonteCarlo struct {
+	Runs       int     `json:"runs"`
+	Resampling string  `json:"resampling"`
+	Ruin       float32 `json:"ruin"`
+}
+
+// The sweep and the walk-forward, the windows are in days
+type Optimisation struct {
+	Grid        string `json:"grid"`
+	Objective   string `json:"objective"`
+	Workers     int    `json:"workers"`
+	InSample    int    `json:"inSample"`
+	OutOfSample int    `json:"outOfSample"`
+}
+
+// The files written by the commands
+type Outputs struct {
+	Journal    []string `json:"journal"`
+	Report     string   `json:"report"`
+	Equity     string   `json:"equity"`
+	MonteCarlo string   `json:"monteCarlo"`
+	Sweep      string   `json:"sweep"`
+	Heatmap    string   `json:"heatmap"`
+}
+
+// An invalid value, Field is its path in the file (e.g. risk.maxExposure)
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (err *FieldError) Error() string {
+	return fmt.Sprintf("%v: %v", err.Field, err.Err)
+}
+
+func (err *FieldError) Unwrap() error {
+	return err.Err
+}
+
+// Read and validate the config file
+func Load(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+
+	config, err := Parse(file)
+	if err != nil {
+		return Config{}, fmt.Errorf("%v: %w", path, err)
+	}
+	return config, nil
+}
+
+// Decode and validate a config, the unknown fields are rejected
+func Parse(r io.Reader) (Config, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, decodeError(content, err)
+	}
+	return config, config.Validate()
+}
+
+// Point the decoding errors at the field or at the line of the file
+func decodeError(content []byte, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		return &FieldError{Field: typeErr.Field, Err: fmt.Errorf("MUST BE %v, GOT %v", typeErr.Type, typeErr.Value)}
+	case errors.As(err, &syntaxErr):
+		line := bytes.Count(content[:syntaxErr.Offset], []byte("\n")) + 1
+		return fmt.Errorf("line %v: %w", line, err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		var raw interface{}
+		if json.Unmarshal(content, &raw) == nil {
+			if field := unknownField(raw, reflect.TypeOf(Config{}), ""); field != "" {
+				return &FieldError{Field: field, Err: fmt.Errorf("UNKNOWN FIELD")}
+			}
+		}
+	}
+	return err
+}
+
+// Retrieve the path of the first field of the decoded JSON that the type doesn't have, empty if there is none
+func unknownField(raw interface{}, t reflect.Type, path string) string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	object, ok := raw.(map[string]interface{})
+	if !ok || t.Kind() != reflect.Struct {
+		return ""
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field, found := t.FieldByNameFunc(func(name string) bool {
+			structField, _ := t.FieldByName(name)
+			return strings.EqualFold(strings.Split(structField.Tag.Get("json"), ",")[0], key)
+		})
+		if !found {
+			return path + key
+		}
+		if unknown := unknownField(object[key], field.Type, path+key+"."); unknown != "" {
+			return unknown
+		}
+	}
+	return ""
+}
+
+// Check every value, the error points at the first invalid field
+func (config Config) Validate() error {
+	invalid := func(field string, err error) error { return &FieldError{Field: field, Err: err} }
+	negative := func(field string, value float32) error {
+		if value < 0 {
+			return invalid(field, fmt.Errorf("MUST NOT BE NEGATIVE"))
+		}
+		return nil
+	}
+
+	symbols := make([]string, 0, len(config.Portfolio))
+	for symbol := range config.Portfolio {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		if allocation := config.Portfolio[symbol]; allocation <= 0 || allocation > 1 {
+			return invalid("portfolio."+symbol, fmt.Errorf("ALLOCATION MUST BE IN (0, 1]"))
+		}
+	}
+
+	dates := []struct {
+		field, value string
+	}{{"from", config.From}, {"start", config.Start}, {"end", config.End}}
+	var previous time.Time
+	for _, date := range dates {
+		if date.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(DateLayout, date.value)
+		if err != nil {
+			return invalid(date.field, fmt.Errorf("INVALID DATE %q, USE YYYY-MM-DD", date.value))
+		}
+		if !parsed.After(previous) {
+			return invalid(date.field, fmt.Errorf("MUST FOLLOW THE PREVIOUS DATE %v", previous.Format(DateLayout)))
+		}
+		previous = parsed
+	}
+
+	if config.Resolution != "" {
+		if _, err := api.ResolutionSeconds(config.Resolution); err != nil {
+			return invalid("resolution", err)
+		}
+	}
+	if err := negative("initialBalance", config.InitialBalance); err != nil {
+		return err
+	}
+
+	if err := config.Strategy.validate(); err != nil {
+		return err
+	}
+	if err := config.Risk.validate(); err != nil {
+		return err
+	}
+
+	monteCarlo := config.Backtest.MonteCarlo
+	if monteCarlo.Runs < 0 {
+		return invalid("backtest.monteCarlo.runs", fmt.Errorf("MUST NOT BE NEGATIVE"))
+	}
+	if monteCarlo.Resampling != "" {
+		if _, err := backtest.ParseResampling(monteCarlo.Resampling); err != nil {
+			return invalid("backtest.monteCarlo.resampling", err)
+		}
+	}
+	if monteCarlo.Ruin < 0 || monteCarlo.Ruin >= 1 {
+		return invalid("backtest.monteCarlo.ruin", fmt.Errorf("MUST BE BETWEEN 0 AND 1"))
+	}
+
+	optimisation := config.Optimisation
+	if optimisation.Grid != "" {
+		if _, err := strategy.ParseGrid(optimisation.Grid); err != nil {
+			return invalid("optimisation.grid", err)
+		}
+	}
+	if optimisation.Objective != "" {
+		if _, err := backtest.ParseObjective(optimisation.Objective); err != nil {
+			return invalid("optimisation.objective", err)
+		}
+	}
+	windows := []struct {
+		name  string
+		value int
+	}{{"workers", optimisation.Workers}, {"inSample", optimisation.InSample}, {"outOfSample", optimisation.OutOfSample}}
+	for _, window := range windows {
+		if window.value < 0 {
+			return invalid("optimisation."+window.name, fmt.Errorf("MUST NOT BE NEGATIVE"))
+		}
+	}
+
+	for i, path := range config.Outputs.Journal {
+		if err := bot.CheckJournalPath(path); err != nil {
+			return invalid(fmt.Sprintf("outputs.journal[%v]", i), err)
+		}
+	}
+	return nil
+}
+
+func (params Strategy) validate() error {
+	invalid := func(field string, err error) error { return &FieldError{Field: "strategy." + field, Err: err} }
+	positive := []struct {
+		name  string
+		value *float32
+	}{{"minDifference", params.MinDifference}, {"stopLoss", params.StopLoss}}
+	for _, field := range positive {
+		if field.value != nil && *field.value <= 0 {
+			return invalid(field.name, fmt.Errorf("MUST BE POSITIVE"))
+		}
+	}
+	notNegative := []struct {
+		name  string
+		value *float32
+	}{
+		{"takeProfit", params.TakeProfit}, {"levelDistance", params.LevelDistance}, {"rewardMargin", params.RewardMargin},
+		{"minRange", params.MinRange}, {"maxRange", params.MaxRange},
+	}
+	for _, field := range notNegative {
+		if field.value != nil && *field.value < 0 {
+			return invalid(field.name, fmt.Errorf("MUST NOT BE NEGATIVE"))
+		}
+	}
+
+	if err := params.Params().Validate(); err != nil {
+		field := "strategy"
+		switch {
+		case params.BufferLength != nil && *params.BufferLength < 3:
+			field = "strategy.bufferLength"
+		case params.MaxRange != nil:
+			field = "strategy.maxRange"
+		case params.MinRange != nil:
+			field = "strategy.minRange"
+		}
+		return &FieldError{Field: field, Err: err}
+	}
+	return nil
+}
+
+// Retrieve the strategy parameters, the missing fields take the default values
+func (params Strategy) Params() strategy.Params {
+	res := strategy.DefaultParams()
+	float := func(field *float32, value *float32) {
+		if value != nil {
+			*field = *value
+		}
+	}
+	float(&res.MinDifference, params.MinDifference)
+	float(&res.StopLossMultiple, params.StopLoss)
+	float(&res.TakeProfitOffset, params.TakeProfit)
+	float(&res.LevelDistance, params.LevelDistance)
+	float(&res.RewardMargin, params.RewardMargin)
+	float(&res.Areas.MinRange, params.MinRange)
+	float(&res.Areas.MaxRange, params.MaxRange)
+	if params.BufferLength != nil {
+		res.Areas.BufferLength = *params.BufferLength
+	}
+	return res
+}
+
+func (risk Risk) validate() error {
+	invalid := func(field string, err error) error { return &FieldError{Field: "risk." + field, Err: err} }
+
+	if risk.Sizer != "" {
+		if _, err := sizing.Parse(risk.Sizer); err != nil {
+			return invalid("sizer", err)
+		}
+	}
+	if risk.MaxExposure < 0 {
+		return invalid("maxExposure", fmt.Errorf("MUST BE POSITIVE"))
+	}
+	if risk.BothHit != "" {
+		if _, err := bot.ParseBothHitRule(risk.BothHit); err != nil {
+			return invalid("bothHit", err)
+		}
+	}
+	if risk.Trailing != "" {
+		if _, err := bot.ParseTrailingStop(risk.Trailing); err != nil {
+			return invalid("trailing", err)
+		}
+	}
+	if risk.BreakEven < 0 {
+		return invalid("breakEven", fmt.Errorf("MUST NOT BE NEGATIVE"))
+	}
+	if len(risk.Ladder) > 0 {
+		if _, err := bot.ParseLadder(joinFloats(risk.Ladder)); err != nil {
+			return invalid("ladder", err)
+		}
+	}
+
+	costs := []struct {
+		name  string
+		value float32
+	}{
+		{"makerFee", risk.Costs.MakerFee}, {"takerFee", risk.Costs.TakerFee}, {"slippage", risk.Costs.Slippage},
+		{"volatilitySlippage", risk.Costs.VolatilitySlippage}, {"shortFunding", risk.Costs.ShortFunding},
+	}
+	for _, cost := range costs {
+		if cost.value < 0 || cost.value >= 1 {
+			return invalid("costs."+cost.name, fmt.Errorf("MUST BE A FRACTION BETWEEN 0 AND 1"))
+		}
+	}
+	return nil
+}
+
+func joinFloats(values []float32) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.FormatFloat(float64(value), 'f', -1, 32)
+	}
+	return strings.Join(parts, ",")
+}
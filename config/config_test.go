@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseFieldErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		field string
+	}{
+		{"unknown field", `{"symbol": "BINANCE:BTCUSDT", "simbol": "x"}`, "simbol"},
+		{"unknown nested field", `{"risk": {"costs": {"takerFe": 0.001}}}`, "risk.costs.takerFe"},
+		{"wrong type", `{"initialBalance": "ten"}`, "initialBalance"},
+		{"wrong nested type", `{"optimisation": {"workers": 1.5}}`, "optimisation.workers"},
+		{"portfolio allocation", `{"portfolio": {"BINANCE:BTCUSDT": 0.5, "BINANCE:ETHUSDT": 1.5}}`, "portfolio.BINANCE:ETHUSDT"},
+		{"date format", `{"from": "01/01/2022"}`, "from"},
+		{"date order", `{"from": "2022-01-01", "start": "2021-12-01"}`, "start"},
+		{"resolution", `{"resolution": "2h"}`, "resolution"},
+		{"initial balance", `{"initialBalance": -1}`, "initialBalance"},
+		{"strategy stop loss", `{"strategy": {"stopLoss": 0}}`, "strategy.stopLoss"},
+		{"strategy take profit", `{"strategy": {"takeProfit": -1}}`, "strategy.takeProfit"},
+		{"strategy buffer length", `{"strategy": {"bufferLength": 1}}`, "strategy.bufferLength"},
+		{"strategy range", `{"strategy": {"minRange": 500, "maxRange": 100}}`, "strategy.maxRange"},
+		{"sizer", `{"risk": {"sizer": "martingale:2"}}`, "risk.sizer"},
+		{"max exposure", `{"risk": {"maxExposure": -2}}`, "risk.maxExposure"},
+		{"both hit", `{"risk": {"bothHit": "random"}}`, "risk.bothHit"},
+		{"trailing", `{"risk": {"trailing": "fixed"}}`, "risk.trailing"},
+		{"break even", `{"risk": {"breakEven": -0.1}}`, "risk.breakEven"},
+		{"ladder", `{"risk": {"ladder": [0.5, 0.2]}}`, "risk.ladder"},
+		{"fee", `{"risk": {"costs": {"takerFee": 1}}}`, "risk.costs.takerFee"},
+		{"funding", `{"risk": {"costs": {"shortFunding": -0.01}}}`, "risk.costs.shortFunding"},
+		{"monte carlo runs", `{"backtest": {"monteCarlo": {"runs": -1}}}`, "backtest.monteCarlo.runs"},
+		{"monte carlo resampling", `{"backtest": {"monteCarlo": {"resampling": "jackknife"}}}`, "backtest.monteCarlo.resampling"},
+		{"monte carlo ruin", `{"backtest": {"monteCarlo": {"ruin": 1}}}`, "backtest.monteCarlo.ruin"},
+		{"grid", `{"optimisation": {"grid": "unknown=1:2:1"}}`, "optimisation.grid"},
+		{"objective", `{"optimisation": {"objective": "luck"}}`, "optimisation.objective"},
+		{"window", `{"optimisation": {"inSample": -30}}`, "optimisation.inSample"},
+		{"journal format", `{"outputs": {"journal": ["trades.csv", "trades.txt"]}}`, "outputs.journal[1]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(test.json))
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("expected an error on %v, got %v", test.field, err)
+			}
+			if fieldErr.Field != test.field {
+				t.Fatalf("expected an error on %v, got %v", test.field, err)
+			}
+		})
+	}
+}
+
+func TestParseValid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"empty", `{}`},
+		{"journal in upper case", `{"outputs": {"journal": ["TRADES.CSV", "trades.Json"]}}`},
+		{"zero strategy parameters", `{"strategy": {"takeProfit": 0, "rewardMargin": 0}}`},
+		{"dates", `{"from": "2021-11-01", "start": "2022-01-01", "end": "2022-02-01"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(test.json)); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	if _, err := Load("../config.example.json"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,6 +3,8 @@ package data
 import (
 	"fmt"
 	"strconv"
+
+	"github.com/frappaf/tradingBot/utils"
 )
 
 //Represents the OHLCV candle data including the timestamp
@@ -13,7 +15,7 @@ type Candle struct {
 
 //Print the data of the candle
 func (candle *Candle) Print() {
-	fmt.Printf(candle.ToString())
+	utils.Printf("%v", candle.ToString())
 }
 
 func (candle *Candle) ToString() string {
@@ -0,0 +1,13 @@
+package data
+
+// Retrieve the candles of the given period in seconds that are closed at the timestamp,
+// the ones still forming (or from the future) are dropped
+func ClosedBefore(candles []Candle, period, timestamp int64) []Candle {
+	var res []Candle
+	for _, candle := range candles {
+		if candle.Timestamp+period <= timestamp {
+			res = append(res, candle)
+		}
+	}
+	return res
+}
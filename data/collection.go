@@ -5,8 +5,6 @@ import (
 	"math"
 	"sort"
 
-	finnhub "github.com/Finnhub-Stock-API/finnhub-go/v2"
-	"github.com/frappaf/tradingBot/api"
 	"github.com/frappaf/tradingBot/utils"
 )
 
@@ -17,7 +15,7 @@ const (
 	SixtyOne
 	SeventyEight
 	Fifty
-	//
+	//Default parameters (see Params)
 	BufferLength int     = 3      //Used in getResistancesAndSupport
 	minRange     float32 = 50.0   //Minimum range for an area
 	maxRange             = 1500.0 //Maxim range for an area
@@ -31,52 +29,31 @@ const (
 //
 //	The High and Open are the highest side of the area
 //	The Low and Close are the lowest side of the area
+//
+// Params tunes the area detection, the zero value uses the defaults
 type Collection struct {
 	History, InterestAreas []Candle
 	Top, Bottom            Candle
 	KeyLevels              []float32
-}
-
-// Get the response for daily resolution
-// Then call FetchDailyData passing the response
-func (collection *Collection) FetchData(from, to int64) error {
-
-	resDaily, err := api.GetResponse("BINANCE:BTCUSDT", "D", from, to)
-	if err != nil {
-		return err
-	}
-
-	collection.FetchDailyData(resDaily)
-	return nil
+	Params                 Params
 }
 
 // Fetch the History of the collection
-// It takes the responses [finnhub.CryptoCandles]
+// It takes the daily candles retrieved by a provider
 // Set the History properly and find the Top and Bottom
-func (collection *Collection) FetchDailyData(res finnhub.CryptoCandles) {
+func (collection *Collection) FetchDailyData(candles []Candle) {
 	top := Candle{}
 	bottom := Candle{}
 	bottom.Low = 0xFFFFF
 
-	for i := 0; i < len(res.GetC()); i++ {
+	for _, candle := range candles {
 
-		var o, c, h, l, v float32
-		var t int64
-
-		o = res.GetO()[i]
-		c = res.GetC()[i]
-		h = res.GetH()[i]
-		l = res.GetL()[i]
-		v = res.GetV()[i]
-		t = res.GetT()[i]
-
-		candle := Candle{Open: o, Close: c, High: h, Low: l, Volume: v, Timestamp: t}
 		collection.History = append(collection.History, candle)
 
-		if top.High < h {
+		if top.High < candle.High {
 			top = candle
 		}
-		if bottom.Low > l {
+		if bottom.Low > candle.Low {
 			bottom = candle
 		}
 
@@ -101,8 +78,22 @@ func (collection *Collection) getFibonacciRetracement() []float32 {
 	return fibRetracement
 }
 
+// Retrieve the fibonacci retracement levels of the history, in the order of the indexes (TwentyThree, ThirtyEight...)
+func (collection *Collection) FibonacciLevels() []float32 {
+	return collection.getFibonacciRetracement()
+}
+
+// Find the interesting areas and the key levels of the whole History
+// They replace the ones found before, so it can be called again every time a day is appended
 func (collection *Collection) FindInterestingAreasAndKeyLevels() {
 
+	//New slices, the callers may still hold the previous ones
+	collection.KeyLevels = nil
+	collection.InterestAreas = nil
+
+	params := collection.Params.WithDefaults()
+	minRange, maxRange := params.MinRange, params.MaxRange
+
 	resSup := collection.getResistancesSupportAndClusters()
 
 	for i := 1; i < len(resSup)-1; {
@@ -187,7 +178,7 @@ func (collection *Collection) FindInterestingAreasAndKeyLevels() {
 }
 
 // Find if exists a resistance or a support in the given buffer
-func (collection *Collection) findResistanceAndSupport(buffer [BufferLength]Candle) (Candle, error) {
+func (collection *Collection) findResistanceAndSupport(buffer []Candle) (Candle, error) {
 
 	minTopShadow := minTopShadow(buffer)
 	maxBody := maxBody(buffer)
@@ -202,7 +193,7 @@ func (collection *Collection) findResistanceAndSupport(buffer [BufferLength]Cand
 			High:      minTopShadow,
 			Low:       maxBody,
 			Volume:    0,
-			Timestamp: buffer[BufferLength-1].Timestamp,
+			Timestamp: buffer[len(buffer)-1].Timestamp,
 		}, nil
 	}
 
@@ -213,7 +204,7 @@ func (collection *Collection) findResistanceAndSupport(buffer [BufferLength]Cand
 			High:      minBody,
 			Low:       minBottomShadow,
 			Volume:    0,
-			Timestamp: buffer[BufferLength-1].Timestamp,
+			Timestamp: buffer[len(buffer)-1].Timestamp,
 		}, nil
 	}
 
@@ -222,14 +213,14 @@ func (collection *Collection) findResistanceAndSupport(buffer [BufferLength]Cand
 }
 
 // Find if exists a cluster in the given buffer
-func (collection *Collection) findClusters(buffer [BufferLength]Candle) (Candle, error) {
+func (collection *Collection) findClusters(buffer []Candle) (Candle, error) {
 
 	//Body of the candle with sign
 	//(positive if is a green candle, negative otherwise)
-	var signedBodies [BufferLength]float32
+	signedBodies := make([]float32, len(buffer))
 
 	//Absolute body of the candles
-	var absoluteBodies [BufferLength]float32
+	absoluteBodies := make([]float32, len(buffer))
 
 	//Calculating the bodies
 	for index, candle := range buffer {
@@ -240,7 +231,7 @@ func (collection *Collection) findClusters(buffer [BufferLength]Candle) (Candle,
 	//Check if there is a cluster in the buffer
 	//We have a cluster if there are two candles with the same direction
 	//and another candle between them with the opposite direction and small body
-	for i := 1; i < BufferLength-1; i++ {
+	for i := 1; i < len(buffer)-1; i++ {
 		if (signedBodies[i-1]*signedBodies[i+1] > 0) &&
 			(signedBodies[i-1]*signedBodies[i] < 0) &&
 			(absoluteBodies[i] < absoluteBodies[i-1] &&
@@ -259,15 +250,13 @@ func (collection *Collection) findClusters(buffer [BufferLength]Candle) (Candle,
 // If it finds [BufferLength] candles with a setup for being a cluster -> cluster
 func (collection *Collection) getResistancesSupportAndClusters() []Candle {
 
-	indexs := make([]int, 2)
-	indexs[0] = BufferLength
-	indexs[1] = 2
+	bufferLength := collection.Params.WithDefaults().BufferLength
 
 	var resSup []Candle
-	var buffer [BufferLength]Candle
+	buffer := make([]Candle, bufferLength)
 
 	for index, candle := range collection.History {
-		if index < BufferLength {
+		if index < bufferLength {
 			buffer[index] = candle
 		} else {
 
@@ -278,8 +267,8 @@ func (collection *Collection) getResistancesSupportAndClusters() []Candle {
 			}
 
 			//Adding the new candle in the buffer and eliminate the first (Simulating a FILO)
-			for i := 1; i <= BufferLength; i++ {
-				if i == BufferLength {
+			for i := 1; i <= bufferLength; i++ {
+				if i == bufferLength {
 					buffer[i-1] = candle
 				} else {
 					buffer[i-1] = buffer[i]
@@ -292,7 +281,7 @@ func (collection *Collection) getResistancesSupportAndClusters() []Candle {
 }
 
 // Find the minimum top shadow of a given buffer of candles
-func minTopShadow(buffer [BufferLength]Candle) float32 {
+func minTopShadow(buffer []Candle) float32 {
 
 	var min float32
 	min = 0xFFFFFF
@@ -306,7 +295,7 @@ func minTopShadow(buffer [BufferLength]Candle) float32 {
 }
 
 // Find the minimum bottom shadow of a given buffer of candles
-func minBottomShadow(buffer [BufferLength]Candle) float32 {
+func minBottomShadow(buffer []Candle) float32 {
 
 	var min float32
 	min = 0xFFFFFF
@@ -320,7 +309,7 @@ func minBottomShadow(buffer [BufferLength]Candle) float32 {
 }
 
 // Find the maximum high body of a given buffer of candles
-func maxBody(buffer [BufferLength]Candle) (res float32) {
+func maxBody(buffer []Candle) (res float32) {
 	var max float32
 
 	for _, candle := range buffer {
@@ -339,7 +328,7 @@ func maxBody(buffer [BufferLength]Candle) (res float32) {
 }
 
 // Find the minimum high body of a given buffer of candles
-func minBody(buffer [BufferLength]Candle) (res float32) {
+func minBody(buffer []Candle) (res float32) {
 	var min float32
 	min = 0xFFFFFF
 	for _, candle := range buffer {
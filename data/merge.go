@@ -0,0 +1,23 @@
+package data
+
+import "sort"
+
+// Merge two series of candles into one sorted by timestamp without duplicates
+// When both series contain a candle with the same timestamp the one from newer is kept
+func Merge(older, newer []Candle) []Candle {
+	byTimestamp := make(map[int64]Candle, len(older)+len(newer))
+	for _, candle := range older {
+		byTimestamp[candle.Timestamp] = candle
+	}
+	for _, candle := range newer {
+		byTimestamp[candle.Timestamp] = candle
+	}
+
+	res := make([]Candle, 0, len(byTimestamp))
+	for _, candle := range byTimestamp {
+		res = append(res, candle)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Timestamp < res[j].Timestamp })
+
+	return res
+}
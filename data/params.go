@@ -0,0 +1,40 @@
+package data
+
+import "fmt"
+
+// The parameters of the area detection
+// An area ranges between MinRange and MaxRange, BufferLength is the number of candles
+// that must share a resistance, a support or a cluster
+// The zero Params takes the default values, otherwise every field is used as it is
+type Params struct {
+	MinRange, MaxRange float32
+	BufferLength       int
+}
+
+// Retrieve the default parameters
+func DefaultParams() Params {
+	return Params{MinRange: minRange, MaxRange: maxRange, BufferLength: BufferLength}
+}
+
+// Retrieve the default parameters in place of the zero Params
+func (params Params) WithDefaults() Params {
+	if params == (Params{}) {
+		return DefaultParams()
+	}
+	return params
+}
+
+// Check that the parameters (with the defaults) can detect areas
+func (params Params) Validate() error {
+	params = params.WithDefaults()
+	if params.MinRange < 0 {
+		return fmt.Errorf("MIN RANGE MUST NOT BE NEGATIVE")
+	}
+	if params.MaxRange <= params.MinRange {
+		return fmt.Errorf("MAX RANGE (%v) MUST BE GREATER THAN MIN RANGE (%v)", params.MaxRange, params.MinRange)
+	}
+	if params.BufferLength < 3 {
+		return fmt.Errorf("BUFFER LENGTH MUST BE AT LEAST 3")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package data
+
+import "sort"
+
+// Epoch offset used to align weekly buckets on monday (1970-01-01 was a thursday)
+const weekOffset int64 = 4 * 24 * 60 * 60
+
+// Retrieve the start of the period of the given seconds containing the timestamp
+// The periods start at a multiple of the period, the weeks on monday
+func PeriodStart(timestamp, period int64) int64 {
+	var offset int64
+	if period == 7*24*60*60 {
+		offset = weekOffset
+	}
+	return timestamp - mod(timestamp-offset, period)
+}
+
+// Aggregate the candles into buckets of the given period in seconds
+// Every bucket starts at a multiple of the period (weeks start on monday)
+// The Open is the first open, the Close the last close, the High and Low the extremes and the Volume the sum
+func Resample(candles []Candle, period int64) []Candle {
+	sorted := make([]Candle, len(candles))
+	copy(sorted, candles)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	var res []Candle
+	for _, candle := range sorted {
+		start := PeriodStart(candle.Timestamp, period)
+
+		if len(res) == 0 || res[len(res)-1].Timestamp != start {
+			candle.Timestamp = start
+			res = append(res, candle)
+			continue
+		}
+
+		last := &res[len(res)-1]
+		last.Close = candle.Close
+		last.Volume += candle.Volume
+		if candle.High > last.High {
+			last.High = candle.High
+		}
+		if candle.Low < last.Low {
+			last.Low = candle.Low
+		}
+	}
+
+	return res
+}
+
+// Positive modulo, used to bucket timestamps before the epoch
+func mod(x, y int64) int64 {
+	r := x % y
+	if r < 0 {
+		r += y
+	}
+	return r
+}
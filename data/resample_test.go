@@ -0,0 +1,34 @@
+package data
+
+import "testing"
+
+func TestPeriodStart(t *testing.T) {
+	const (
+		minute = int64(60)
+		day    = 24 * 60 * minute
+		week   = 7 * day
+		monday = int64(1641168000) //2022-01-03
+	)
+
+	tests := []struct {
+		name              string
+		timestamp, period int64
+		expected          int64
+	}{
+		{"start of a half hour", monday + 30*minute, 30 * minute, monday + 30*minute},
+		{"inside a half hour", monday + 45*minute, 30 * minute, monday + 30*minute},
+		{"inside a day", monday + day + 5*minute, day, monday + day},
+		{"monday", monday, week, monday},
+		{"sunday", monday + 6*day + 23*60*minute, week, monday},
+		{"thursday", monday + 3*day, week, monday},
+		{"before the epoch", -1, day, -day},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := PeriodStart(test.timestamp, test.period); got != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/frappaf/tradingBot/api"
+)
+
+// Download the candles of the symbol (filling the cache) and write them to a file usable with --data-file
+func runFetch(flags *flag.FlagSet, args []string) error {
+	source := addDataFlags(flags)
+	symbol := addSymbolFlag(flags)
+	from := flags.String("from", defaultFrom, "Start of the candles (YYYY-MM-DD)")
+	end := flags.String("end", "", "End of the candles (YYYY-MM-DD), now if empty")
+	resolution := flags.String("resolution", "30", "Resolution of the candles: 1, 5, 15, 30, 60 minutes or D")
+	outputFlags := addOutputFlags(flags, ".")
+	outFile := flags.String("out", "", "CSV or JSON file receiving the candles, SYMBOL_RESOLUTION.csv if empty")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	fromTimestamp, err := parseDate("from", *from)
+	if err != nil {
+		return err
+	}
+	to := time.Now().Unix()
+	if *end != "" {
+		if to, err = parseDate("end", *end); err != nil {
+			return err
+		}
+	}
+	if _, err := api.ResolutionSeconds(*resolution); err != nil {
+		return err
+	}
+	outputs, err := outputFlags()
+	if err != nil {
+		return err
+	}
+	if *outFile == "" {
+		*outFile = api.FileName(*symbol) + "_" + *resolution + ".csv"
+	}
+
+	candles, err := source().GetCandles(*symbol, *resolution, fromTimestamp, to)
+	if err != nil {
+		return err
+	}
+	if len(candles) == 0 {
+		return fmt.Errorf("NO CANDLES OF %v BETWEEN %v AND %v", *symbol, *from, time.Unix(to, 0).UTC().Format(dateLayout))
+	}
+
+	path := outputs.path(*outFile)
+	if err := api.SaveCandles(path, candles); err != nil {
+		return err
+	}
+	first, last := candles[0].Timestamp, candles[len(candles)-1].Timestamp
+	fmt.Printf("%v candles of %v from %v to %v written to %v\n", len(candles), *symbol,
+		time.Unix(first, 0).UTC().Format("2006-01-02 15:04"), time.Unix(last, 0).UTC().Format("2006-01-02 15:04"), path)
+	return nil
+}
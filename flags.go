@@ -0,0 +1,376 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frappaf/tradingBot/api"
+	"github.com/frappaf/tradingBot/backtest"
+	"github.com/frappaf/tradingBot/bot"
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/config"
+	"github.com/frappaf/tradingBot/sizing"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+const (
+	defaultCacheDir = ".cache/candles"
+	defaultSymbol   = "BINANCE:BTCUSDT"
+	dateLayout      = config.DateLayout
+	defaultFrom     = "2020-01-01"
+	defaultStart    = "2022-01-01"
+	defaultBalance  = 10000
+)
+
+// Build the candle provider
+// If dataFile is set the candles are read from it, otherwise they are downloaded from finnhub and cached in cacheDir
+// If apiURL is set the candles are downloaded from it (e.g. the stub server) and never cached
+func newProvider(dataFile, cacheDir, apiURL string) api.CandleProvider {
+	if dataFile != "" {
+		return &api.FileProvider{Path: dataFile}
+	}
+
+	finnhubProvider := api.NewFinnhubProvider()
+	if apiURL != "" {
+		finnhubProvider.BaseURL = apiURL
+		return finnhubProvider
+	}
+
+	if cacheDir != "" {
+		return &api.CachedProvider{Source: finnhubProvider, Dir: cacheDir}
+	}
+	return finnhubProvider
+}
+
+const paramsUsage = "Parameters of the strategy, as NAME=VALUE pairs separated by commas (min-difference, stop-loss, take-profit, level-distance, reward-margin, min-range, max-range, buffer-length), the missing ones take the default values"
+
+const portfolioUsage = "Trade several symbols sharing the balance, as SYMBOL=ALLOCATION pairs (e.g. BINANCE:BTCUSDT=0.6,BINANCE:ETHUSDT=0.4), all the symbols use the same --params"
+
+// Parse the portfolio flag, a comma separated list of SYMBOL=ALLOCATION pairs
+func parseAllocations(value string) (map[string]float32, error) {
+	allocations := make(map[string]float32)
+
+	for _, pair := range strings.Split(value, ",") {
+		symbol, allocation, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || symbol == "" {
+			return nil, fmt.Errorf("INVALID PORTFOLIO ENTRY %q, USE SYMBOL=ALLOCATION", pair)
+		}
+		parsed, err := strconv.ParseFloat(allocation, 32)
+		if err != nil {
+			return nil, fmt.Errorf("INVALID ALLOCATION %q FOR %v", allocation, symbol)
+		}
+		allocations[symbol] = float32(parsed)
+	}
+
+	return allocations, nil
+}
+
+// Add the flags of the trading costs to the flag set
+// The returned function retrieves the costs after the parsing
+func addCostFlags(flags *flag.FlagSet) func() broker.Costs {
+	makerFee := flags.Float64("maker-fee", 0, "Fee of the take profits (orders resting at their level) as a fraction of the notional (e.g. 0.0002)")
+	takerFee := flags.Float64("taker-fee", 0, "Fee of the entries, the stopLoss and the other market exits as a fraction of the notional (e.g. 0.001)")
+	slippage := flags.Float64("slippage", 0, "Slippage as a fraction of the price")
+	volatilitySlippage := flags.Float64("volatility-slippage", 0, "Slippage as a fraction of the range of the last candle")
+	shortFunding := flags.Float64("short-funding", 0, "Daily funding of the short positions as a fraction of the notional")
+
+	return func() broker.Costs {
+		return broker.Costs{
+			MakerFee:           float32(*makerFee),
+			TakerFee:           float32(*takerFee),
+			FixedSlippage:      float32(*slippage),
+			VolatilitySlippage: float32(*volatilitySlippage),
+			ShortFunding:       float32(*shortFunding),
+		}
+	}
+}
+
+// Add the flags of the position sizing to the flag set
+// The returned function retrieves the sizer and the max exposure after the parsing
+func addSizingFlags(flags *flag.FlagSet) func() (sizing.Sizer, float32, error) {
+	sizer := flags.String("sizer", "all-in", "Position sizer: all-in, fixed-fraction:RISK, fixed-notional:NOTIONAL, kelly:FRACTION or volatility:TARGET")
+	maxExposure := flags.Float64("max-exposure", 1, "Maximum notional of a position as a multiple of the balance")
+
+	return func() (sizing.Sizer, float32, error) {
+		if *maxExposure <= 0 {
+			return nil, 0, fmt.Errorf("MAX EXPOSURE MUST BE POSITIVE")
+		}
+		positionSizer, err := sizing.Parse(*sizer)
+		return positionSizer, float32(*maxExposure), err
+	}
+}
+
+// The exit management of the positions
+type exitSettings struct {
+	trailing  bot.TrailingStop
+	breakEven float32
+	ladder    []float32
+}
+
+// Apply the exit settings to a bot
+func (settings exitSettings) configure(exitBot *bot.Bot) {
+	exitBot.Trailing = settings.trailing
+	exitBot.BreakEven = settings.breakEven
+	exitBot.Ladder = settings.ladder
+}
+
+// Add the flags of the exit management to the flag set
+// The returned function retrieves the settings after the parsing
+func addExitFlags(flags *flag.FlagSet) func() (exitSettings, error) {
+	trailing := flags.String("trailing", "", "Trailing stop: fixed:DISTANCE, atr:MULTIPLE or key-levels, empty to disable it")
+	breakEven := flags.Float64("break-even", 0, "Move the stopLoss to the entry price once the profit reaches this fraction of the entry price, 0 to disable it")
+	ladder := flags.String("ladder", "", "Fractions of the position closed at the successive take profits (e.g. 0.5,0.3,0.2), empty to close it at once")
+
+	return func() (exitSettings, error) {
+		settings := exitSettings{breakEven: float32(*breakEven)}
+		if *breakEven < 0 {
+			return settings, fmt.Errorf("BREAK EVEN MUST NOT BE NEGATIVE")
+		}
+
+		var err error
+		if *trailing != "" {
+			if settings.trailing, err = bot.ParseTrailingStop(*trailing); err != nil {
+				return settings, err
+			}
+		}
+		if *ladder != "" {
+			if settings.ladder, err = bot.ParseLadder(*ladder); err != nil {
+				return settings, err
+			}
+		}
+		return settings, nil
+	}
+}
+
+// Add the flags of the costs, the sizing and the exits of a replay to the flag set, shared by the backtest and the optimisations
+// The returned function applies them to the config after the parsing
+func addRiskFlags(flags *flag.FlagSet) func(*backtest.Config) error {
+	costs := addCostFlags(flags)
+	bothHit := flags.String("both-hit", "stop-loss", "Exit of a candle touching both the stopLoss and the takeProfit: stop-loss, take-profit or nearest (to the open)")
+	sizer := addSizingFlags(flags)
+	exits := addExitFlags(flags)
+
+	return func(config *backtest.Config) error {
+		positionSizer, maxExposure, err := sizer()
+		if err != nil {
+			return err
+		}
+		exit, err := exits()
+		if err != nil {
+			return err
+		}
+		rule, err := bot.ParseBothHitRule(*bothHit)
+		if err != nil {
+			return err
+		}
+
+		config.Costs = costs()
+		config.BothHit = rule
+		config.Sizer = positionSizer
+		config.MaxExposure = maxExposure
+		config.Trailing = exit.trailing
+		config.BreakEven = exit.breakEven
+		config.Ladder = exit.ladder
+		return nil
+	}
+}
+
+// Add the flag of the trade journal to the flag set
+// The returned function retrieves the journal (nil if disabled) inside the output directory after the parsing
+func addJournalFlag(flags *flag.FlagSet) func(outputSettings) (*bot.Journal, error) {
+	paths := flags.String("journal", "", "Comma separated CSV or JSON files receiving the closed trades (e.g. trades.csv,trades.json), empty to disable it")
+
+	return func(outputs outputSettings) (*bot.Journal, error) {
+		if *paths == "" {
+			return nil, nil
+		}
+		var files []string
+		for _, path := range strings.Split(*paths, ",") {
+			files = append(files, outputs.path(path))
+		}
+		return bot.NewJournal(files...)
+	}
+}
+
+// Add the flags of the Monte Carlo resampling of the trades to the flag set
+// The returned function retrieves the settings after the parsing, the runs are 0 when it is disabled
+func addMonteCarloFlags(flags *flag.FlagSet) func() (backtest.MonteCarlo, error) {
+	runs := flags.Int("monte-carlo", 0, "Number of Monte Carlo runs resampling the trades, 0 to disable it")
+	resampling := flags.String("resampling", "bootstrap", "Resampling of the Monte Carlo: bootstrap (with replacement) or shuffle (reorder)")
+	ruin := flags.Float64("ruin", 0.5, "Equity, as a fraction of the initial balance, at which a Monte Carlo run is ruined")
+
+	return func() (backtest.MonteCarlo, error) {
+		settings := backtest.MonteCarlo{Runs: *runs, RuinLevel: float32(*ruin)}
+		if *runs < 0 {
+			return settings, fmt.Errorf("MONTE CARLO RUNS MUST NOT BE NEGATIVE")
+		}
+		if *ruin <= 0 || *ruin >= 1 {
+			return settings, fmt.Errorf("RUIN MUST BE BETWEEN 0 AND 1")
+		}
+		var err error
+		settings.Resampling, err = backtest.ParseResampling(*resampling)
+		return settings, err
+	}
+}
+
+// Parse the flags of a command
+// If --config is passed the values of the file are applied to the flags that are not on the command line,
+// the fields that the command has no flag for belong to the other commands and are skipped
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	configFile := flags.String("config", "", "JSON config file, the flags passed explicitly override its values")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("UNEXPECTED ARGUMENT %q, SEE %v --help", flags.Arg(0), flags.Name())
+	}
+	if *configFile == "" {
+		return nil
+	}
+
+	settings, err := config.Load(*configFile)
+	if err != nil {
+		return fmt.Errorf("INVALID CONFIG %w", err)
+	}
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for _, value := range configValues(settings) {
+		if flags.Lookup(value.flag) == nil || explicit[value.flag] {
+			continue
+		}
+		if err := flags.Set(value.flag, value.value); err != nil {
+			return fmt.Errorf("INVALID CONFIG %v: %w", *configFile, &config.FieldError{Field: value.field, Err: err})
+		}
+	}
+	return nil
+}
+
+// Add the flags of the candle source to the flag set
+// The returned function builds the provider after the parsing (see newProvider)
+func addDataFlags(flags *flag.FlagSet) func() api.CandleProvider {
+	dataFile := flags.String("data-file", "", "CSV or JSON file with the OHLCV history to use instead of finnhub, {symbol} in the path reads a file for every symbol (e.g. testdata/{symbol}_30.json)")
+	cacheDir := flags.String("cache-dir", defaultCacheDir, "Directory of the candle cache, empty to disable it")
+	apiURL := flags.String("api-url", "", "Base URL of a finnhub compatible API (e.g. the stub server)")
+
+	return func() api.CandleProvider {
+		return newProvider(*dataFile, *cacheDir, *apiURL)
+	}
+}
+
+// Add the flag of the traded symbol to the flag set
+func addSymbolFlag(flags *flag.FlagSet) *string {
+	return flags.String("symbol", defaultSymbol, "Symbol to trade, crypto pairs have the exchange prefix (e.g. BINANCE:ETHUSDT, AAPL)")
+}
+
+// Where the files are written and how much is logged
+// The relative paths of the outputs are inside dir, quiet hides the logs of the bots and keeps the results
+type outputSettings struct {
+	dir   string
+	quiet bool
+}
+
+// Add the flags of the outputs to the flag set
+// The returned function creates the output directory and retrieves the settings after the parsing
+func addOutputFlags(flags *flag.FlagSet, defaultDir string) func() (outputSettings, error) {
+	dir := flags.String("out-dir", defaultDir, "Directory of the output files given with a relative path")
+	quiet := flags.Bool("quiet", false, "Hide the logs of the bots (positions, areas...) and print only the results")
+
+	return func() (outputSettings, error) {
+		settings := outputSettings{dir: *dir, quiet: *quiet}
+		if *dir != "" && *dir != "." {
+			if err := os.MkdirAll(*dir, 0o755); err != nil {
+				return settings, err
+			}
+		}
+		return settings, nil
+	}
+}
+
+// Retrieve the path of an output file, empty if it is disabled
+func (settings outputSettings) path(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(settings.dir, name)
+}
+
+// Retrieve the logger of the bots, it discards the logs if quiet
+func (settings outputSettings) logger() *utils.Logger {
+	if settings.quiet {
+		return utils.Discard
+	}
+	return utils.Default
+}
+
+// Parse the date of the named flag as midnight UTC
+func parseDate(name, value string) (int64, error) {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("INVALID %v DATE %q, USE YYYY-MM-DD", strings.ToUpper(name), value)
+	}
+	return date.Unix(), nil
+}
+
+// Add the flags of the start of the daily history and of the initial balance to the flag set
+// The returned function retrieves the start timestamp and the balance after the parsing
+func addAccountFlags(flags *flag.FlagSet) func() (int64, float32, error) {
+	from := flags.String("from", defaultFrom, "Start of the daily history that initializes the strategy (YYYY-MM-DD)")
+	balance := flags.Float64("balance", defaultBalance, "Initial balance")
+
+	return func() (int64, float32, error) {
+		fromTimestamp, err := parseDate("from", *from)
+		if err != nil {
+			return 0, 0, err
+		}
+		if *balance <= 0 {
+			return 0, 0, fmt.Errorf("BALANCE MUST BE POSITIVE")
+		}
+		return fromTimestamp, float32(*balance), nil
+	}
+}
+
+// The replayed candles: from start until end (now if 0) with the resolution
+type replaySettings struct {
+	start, end int64
+	resolution string
+}
+
+// Add the flags of the replayed candles to the flag set
+// The returned function retrieves the settings after the parsing
+func addReplayFlags(flags *flag.FlagSet, startUsage, endUsage string) func() (replaySettings, error) {
+	start := flags.String("start", defaultStart, startUsage+" (YYYY-MM-DD)")
+	end := flags.String("end", "", endUsage+" (YYYY-MM-DD), now if empty")
+	resolution := flags.String("resolution", "30", "Resolution of the replayed candles: 1, 5, 15, 30, 60 minutes or D")
+
+	return func() (replaySettings, error) {
+		settings := replaySettings{resolution: *resolution}
+		if _, err := api.ResolutionSeconds(*resolution); err != nil {
+			return settings, err
+		}
+		var err error
+		if settings.start, err = parseDate("start", *start); err != nil {
+			return settings, err
+		}
+		if *end != "" {
+			if settings.end, err = parseDate("end", *end); err != nil {
+				return settings, err
+			}
+		}
+		return settings, nil
+	}
+}
+
+// Load once the daily and the replayed candles of the symbol from the start of the history until the end of the replay
+// so that the concurrent backtests share them instead of hitting the provider
+func loadDataset(provider api.CandleProvider, symbol string, from int64, replay replaySettings) (*backtest.Dataset, error) {
+	end := replay.end
+	if end == 0 {
+		end = time.Now().Unix()
+	}
+	return backtest.LoadDataset(provider, symbol, from, end, "D", replay.resolution)
+}
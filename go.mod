@@ -3,8 +3,11 @@ module github.com/frappaf/tradingBot
 go 1.18
 
 require (
-	github.com/Finnhub-Stock-API/finnhub-go/v2 v2.0.13 // indirect
-	github.com/ably/ably-go v1.2.8 // indirect
+	github.com/Finnhub-Stock-API/finnhub-go/v2 v2.0.13
+	github.com/ably/ably-go v1.2.8
+)
+
+require (
 	github.com/golang/protobuf v1.4.2 // indirect
 	github.com/klauspost/compress v1.15.9 // indirect
 	github.com/ugorji/go/codec v1.2.7 // indirect
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/strategy"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// Print the interest areas, the key levels and the fibonacci levels the strategy finds in the daily history
+func runLevels(flags *flag.FlagSet, args []string) error {
+	source := addDataFlags(flags)
+	symbol := addSymbolFlag(flags)
+	paramsFlag := flags.String("params", "", paramsUsage)
+	from := flags.String("from", defaultFrom, "Start of the daily history (YYYY-MM-DD)")
+	date := flags.String("date", "", "Day of the levels (YYYY-MM-DD), only the days closed before it are used, today if empty")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	fromTimestamp, err := parseDate("from", *from)
+	if err != nil {
+		return err
+	}
+	at := time.Now().Unix()
+	if *date != "" {
+		if at, err = parseDate("date", *date); err != nil {
+			return err
+		}
+	}
+	params, err := strategy.ParseParams(*paramsFlag)
+	if err != nil {
+		return err
+	}
+
+	const day = 24 * 60 * 60
+	daily, err := source().GetCandles(*symbol, "D", fromTimestamp, at)
+	if err != nil {
+		return err
+	}
+	daily = data.ClosedBefore(daily, day, at)
+	if len(daily) == 0 {
+		return fmt.Errorf("NO DAILY CANDLES OF %v BEFORE %v", *symbol, time.Unix(at, 0).UTC().Format(dateLayout))
+	}
+
+	breakout := &strategy.Breakout{Params: params}
+	breakout.Initialize(daily)
+
+	body := fmt.Sprintf("%v on %v from %v daily candles (%v)", *symbol, time.Unix(at, 0).UTC().Format(dateLayout), len(daily), breakout.Params)
+	body += "\n\nInterest areas:"
+	for _, area := range breakout.InterestAreas() {
+		top, bottom := area.High, area.Low
+		if top < bottom {
+			top, bottom = bottom, top
+		}
+		body += fmt.Sprintf("\n  %.2f - %.2f", bottom, top)
+	}
+	body += "\n\nKey levels:"
+	for _, level := range breakout.KeyLevels() {
+		body += fmt.Sprintf("\n  %.2f", level)
+	}
+	body += "\n\nFibonacci retracement:"
+	names := []string{"23.6%", "38.2%", "61.8%", "78.6%", "50%"}
+	for i, level := range breakout.FibonacciLevels() {
+		if i < len(names) {
+			body += fmt.Sprintf("\n  %v: %.2f", names[i], level)
+		}
+	}
+	utils.PrintStatus("LEVELS", body)
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/frappaf/tradingBot/bot"
+	"github.com/frappaf/tradingBot/broker"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+// Initialize the bot with the daily history until now and trade the live price on a paper broker
+func runLive(flags *flag.FlagSet, args []string) error {
+	data := addDataFlags(flags)
+	symbol := addSymbolFlag(flags)
+	channel := flags.String("channel", "", "Ably channel streaming the live price, derived from the symbol if empty (a portfolio takes it only with a single symbol)")
+	ablyKey := flags.String("ably-key", "", "Ably key of the live price stream, the public coindesk key if empty")
+	portfolioFlag := flags.String("portfolio", "", portfolioUsage)
+	paramsFlag := flags.String("params", "", paramsUsage)
+	account := addAccountFlags(flags)
+	costs := addCostFlags(flags)
+	sizer := addSizingFlags(flags)
+	exits := addExitFlags(flags)
+	outputFlags := addOutputFlags(flags, ".")
+	journalFlag := addJournalFlag(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	from, balance, err := account()
+	if err != nil {
+		return err
+	}
+	params, err := strategy.ParseParams(*paramsFlag)
+	if err != nil {
+		return err
+	}
+	positionSizer, maxExposure, err := sizer()
+	if err != nil {
+		return err
+	}
+	exit, err := exits()
+	if err != nil {
+		return err
+	}
+	outputs, err := outputFlags()
+	if err != nil {
+		return err
+	}
+	journal, err := journalFlag(outputs)
+	if err != nil {
+		return err
+	}
+
+	to := time.Now().Unix()
+	if *portfolioFlag != "" {
+		allocations, err := parseAllocations(*portfolioFlag)
+		if err != nil {
+			return err
+		}
+		//A channel streams the price of one symbol
+		if *channel != "" && len(allocations) > 1 {
+			return fmt.Errorf("THE CHANNEL STREAMS A SINGLE SYMBOL, THE PORTFOLIO HAS %v", len(allocations))
+		}
+		//All the symbols share the parameters of the strategy
+		breakout := func(string) strategy.Strategy { return &strategy.Breakout{Params: params} }
+		portfolio, err := bot.NewPortfolio(data(), breakout, balance, allocations, from, to)
+		if err != nil {
+			return err
+		}
+		portfolio.Broker.Costs = costs()
+		for _, symbolBot := range portfolio.Bots {
+			symbolBot.Sizer = positionSizer
+			symbolBot.MaxExposure = maxExposure
+			exit.configure(symbolBot)
+			symbolBot.Journal = journal
+			symbolBot.Channel = *channel
+			symbolBot.AblyKey = *ablyKey
+			symbolBot.SetLogger(outputs.logger())
+		}
+		return runUntilStopped(portfolio.Run, journal)
+	}
+
+	paper := broker.NewPaper(balance)
+	paper.Costs = costs()
+	liveBot := bot.Bot{
+		Strategy:    &strategy.Breakout{Params: params},
+		Provider:    data(),
+		Broker:      paper,
+		Channel:     *channel,
+		AblyKey:     *ablyKey,
+		Sizer:       positionSizer,
+		MaxExposure: maxExposure,
+		Logger:      outputs.logger(),
+	}
+	exit.configure(&liveBot)
+	liveBot.Journal = journal
+	if err := liveBot.Initialize(*symbol, balance, from, to); err != nil {
+		return err
+	}
+	return runUntilStopped(liveBot.Run, journal)
+}
+
+// Run the live bots until they fail or the process is interrupted (SIGINT or SIGTERM)
+// Then the journal (if any) is closed, so the JSON files receive the last trades
+func runUntilStopped(run func() error, journal *bot.Journal) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	done := make(chan error, 1)
+	go func() { done <- run() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-stop:
+	}
+	if journal != nil {
+		if closeErr := journal.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
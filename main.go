@@ -3,34 +3,107 @@ package main
 //Made by Francesco Pippo (FrappaF)
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"time"
-
-	"github.com/frappaf/tradingBot/backtest"
-	"github.com/frappaf/tradingBot/bot"
+	"strings"
 )
 
-func main() {
+// A subcommand of the CLI, run parses its flags from args
+type command struct {
+	name, summary, description string
+	run                        func(flags *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"live", "Trade the live price on a paper broker",
+		"Initialize the bot with the daily history until now, then stream the live price and trade it on a paper broker.", runLive},
+	{"backtest", "Replay the history and print the metrics",
+		"Initialize the bot with the daily history before --start, replay the candles until --end and print the metrics,\nthe benchmarks and optionally the Monte Carlo of the trades.", runBacktest},
+	{"levels", "Print the areas and the levels of the strategy",
+		"Print the interest areas, the key levels and the fibonacci levels found in the daily history closed before --date.", runLevels},
+	{"fetch", "Download candles to a file",
+		"Download the candles of the symbol (filling the cache) and write them to a CSV or JSON file usable with --data-file.", runFetch},
+	{"sweep", "Backtest a grid of parameters",
+		"Backtest every combination of --grid on the same candles, concurrently, and rank them by --objective.", runSweep},
+	{"walkforward", "Tune the parameters on rolling windows",
+		"Pick the best combination of --grid on every in-sample window and trade it on the following out-of-sample window.", runWalkForward},
+	{"report", "Write the report of a backtest",
+		"Run a backtest of a single symbol and write report.html, trades.csv, equity.csv and, with --monte-carlo,\nmonte-carlo.csv in --out-dir.", runReport},
+	{"stub", "Serve candle fixtures like finnhub",
+		"Serve the candle fixtures on the /crypto/candle endpoint with the finnhub JSON shape, to run without the internet.", runStub},
+}
+
+// The old names of the commands
+var aliases = map[string]string{"test": "backtest"}
+
+// Print the commands
+func usage() {
+	fmt.Println("Usage: go run . COMMAND [flags]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-12v %v\n", cmd.name, cmd.summary)
+	}
+	fmt.Println()
+	fmt.Println("Run go run . COMMAND --help to see the flags of a command.")
+}
+
+// Find the command with the given name or alias
+func lookup(name string) (command, bool) {
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Build the flag set of the command, its --help prints the description and the flags
+func (cmd command) flags() *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	flags.Usage = func() {
+		output := flags.Output()
+		fmt.Fprintf(output, "Usage: go run . %v [flags]\n\n%v\n\nFlags:\n", cmd.name, cmd.description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
 
-	from := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.Local).Unix()
+func main() {
 
 	args := os.Args[1:]
-	if !(len(args) > 0) {
-		fmt.Println("COMMAND NOT FOUND TRY live OR test")
+	if len(args) == 0 {
+		usage()
+		os.Exit(-1)
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			if cmd, ok := lookup(args[1]); ok {
+				cmd.run(cmd.flags(), []string{"--help"}) //Exits after printing the flags
+			}
+		}
+		usage()
+		return
+	}
+
+	cmd, ok := lookup(args[0])
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for _, cmd := range commands {
+			names = append(names, cmd.name)
+		}
+		fmt.Printf("UNKNOWN COMMAND %q, USE %v\n", args[0], strings.Join(names, ", "))
 		os.Exit(-1)
 	}
 
-	if args[0] == "live" {
-		to := time.Now().Unix()
-		btcBot := bot.Bot{}
-		btcBot.Initialize(10000, from, to)
-		btcBot.Run()
-	} else if args[0] == "test" {
-		to := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.Local).Unix()
-		backtest.RunBacktest(from, to, "30")
-	} else {
-		fmt.Println("COMMAND NOT VALID TRY live OR test")
+	if err := cmd.run(cmd.flags(), args[1:]); err != nil {
+		fmt.Println(err)
 		os.Exit(-1)
 	}
 
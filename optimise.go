@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/frappaf/tradingBot/backtest"
+	"github.com/frappaf/tradingBot/report"
+	"github.com/frappaf/tradingBot/strategy"
+)
+
+// The combinations tried by an optimisation and how they are ranked
+type gridSettings struct {
+	params    []strategy.Params
+	objective backtest.Objective
+	workers   int
+}
+
+// Add the flags of the grid of parameters to the flag set
+// The returned function retrieves the settings after the parsing
+func addGridFlags(flags *flag.FlagSet, objectiveUsage string) func() (gridSettings, error) {
+	grid := flags.String("grid", "min-difference=200,300,400;stop-loss=1,1.5,2", "Parameters to try, as NAME=VALUE,VALUE entries separated by semicolons (min-difference, stop-loss, take-profit, level-distance, reward-margin, min-range, max-range, buffer-length)")
+	objective := flags.String("objective", "sharpe", objectiveUsage+": sharpe, sortino, return or profit-factor")
+	workers := flags.Int("workers", 0, "Backtests running concurrently, the number of CPUs if 0")
+
+	return func() (gridSettings, error) {
+		settings := gridSettings{workers: *workers}
+		if *workers < 0 {
+			return settings, fmt.Errorf("WORKERS MUST NOT BE NEGATIVE")
+		}
+		var err error
+		if settings.params, err = strategy.ParseGrid(*grid); err != nil {
+			return settings, err
+		}
+		settings.objective, err = backtest.ParseObjective(*objective)
+		return settings, err
+	}
+}
+
+// Backtest every combination of the grid on the same period and rank them
+func runSweep(flags *flag.FlagSet, args []string) error {
+	data := addDataFlags(flags)
+	symbol := addSymbolFlag(flags)
+	account := addAccountFlags(flags)
+	replay := addReplayFlags(flags, "Start of the replay", "End of the replay")
+	gridFlags := addGridFlags(flags, "Metric ranking the combinations")
+	risk := addRiskFlags(flags)
+	outputFlags := addOutputFlags(flags, ".")
+	outFile := flags.String("out", "sweep.csv", "CSV file receiving the ranked combinations")
+	heatmapFile := flags.String("heatmap", "", "HTML file receiving the heatmap of the score over the x and y parameters, empty to disable it")
+	xParam := flags.String("x", "min-difference", "Parameter on the horizontal axis of the heatmap")
+	yParam := flags.String("y", "stop-loss", "Parameter on the vertical axis of the heatmap")
+	top := flags.Int("top", 10, "Combinations printed")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	from, balance, err := account()
+	if err != nil {
+		return err
+	}
+	replaySettings, err := replay()
+	if err != nil {
+		return err
+	}
+	grid, err := gridFlags()
+	if err != nil {
+		return err
+	}
+	outputs, err := outputFlags()
+	if err != nil {
+		return err
+	}
+	dataset, err := loadDataset(data(), *symbol, from, replaySettings)
+	if err != nil {
+		return err
+	}
+
+	config := backtest.Config{
+		Symbol:         *symbol,
+		From:           from,
+		To:             replaySettings.start,
+		End:            replaySettings.end,
+		Resolution:     replaySettings.resolution,
+		InitialBalance: balance,
+		BenchmarkRuns:  -1,
+	}
+	if err := risk(&config); err != nil {
+		return err
+	}
+
+	fmt.Printf("Running %v backtests\n", len(grid.params))
+	sweep, err := backtest.RunSweep(dataset, config, grid.params, grid.workers, grid.objective)
+	if err != nil {
+		return err
+	}
+
+	for _, combination := range sweep {
+		if combination.Rank > *top {
+			break
+		}
+		fmt.Printf("%v. %v\tScore: %.2f\tReturn: %.2f%%\tMax drawdown: %.2f%%\tTrades: %v\n", combination.Rank, combination.Params,
+			combination.Score, combination.Result.TotalReturn*100, combination.Result.MaxDrawdown*100, combination.Result.NumberOfTrades)
+	}
+
+	if err := backtest.SaveSweep(outputs.path(*outFile), sweep); err != nil {
+		return err
+	}
+	if *heatmapFile != "" {
+		return report.SaveHeatmap(outputs.path(*heatmapFile), sweep, *xParam, *yParam)
+	}
+	return nil
+}
+
+// Tune the parameters on rolling in-sample windows and trade them on the following out-of-sample windows
+func runWalkForward(flags *flag.FlagSet, args []string) error {
+	data := addDataFlags(flags)
+	symbol := addSymbolFlag(flags)
+	account := addAccountFlags(flags)
+	replay := addReplayFlags(flags, "Start of the first in-sample window", "End of the last out-of-sample window")
+	inSample := flags.Int("in-sample", 90, "Days of every in-sample window")
+	outOfSample := flags.Int("out-of-sample", 30, "Days of every out-of-sample window")
+	gridFlags := addGridFlags(flags, "Metric maximised in-sample")
+	risk := addRiskFlags(flags)
+	outputFlags := addOutputFlags(flags, ".")
+	equityFile := flags.String("equity", "", "CSV file receiving the joined out-of-sample equity curve, empty to disable it")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	from, balance, err := account()
+	if err != nil {
+		return err
+	}
+	replaySettings, err := replay()
+	if err != nil {
+		return err
+	}
+	grid, err := gridFlags()
+	if err != nil {
+		return err
+	}
+	outputs, err := outputFlags()
+	if err != nil {
+		return err
+	}
+	dataset, err := loadDataset(data(), *symbol, from, replaySettings)
+	if err != nil {
+		return err
+	}
+
+	config := backtest.Config{
+		Symbol:         *symbol,
+		From:           from,
+		To:             replaySettings.start,
+		End:            replaySettings.end,
+		Resolution:     replaySettings.resolution,
+		InitialBalance: balance,
+	}
+	if err := risk(&config); err != nil {
+		return err
+	}
+
+	const day = 24 * 60 * 60
+	res, err := backtest.RunWalkForward(dataset, backtest.WalkForward{
+		Config:      config,
+		InSample:    int64(*inSample) * day,
+		OutOfSample: int64(*outOfSample) * day,
+		Grid:        grid.params,
+		Objective:   grid.objective,
+		Workers:     grid.workers,
+	})
+	if err != nil {
+		return err
+	}
+	res.Print()
+
+	if *equityFile != "" {
+		return backtest.SaveEquity(outputs.path(*equityFile), res.OutOfSample.Equity)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/frappaf/tradingBot/backtest"
+)
+
+const cellSize = 70.0
+
+// Write a self-contained HTML heatmap of the score of a sweep over two parameters
+// When the sweep varies other parameters every cell shows the best score among them
+func WriteHeatmap(w io.Writer, sweep []backtest.SweepResult, xParam, yParam string) error {
+	if len(sweep) == 0 {
+		return fmt.Errorf("THE SWEEP IS EMPTY")
+	}
+
+	type key struct{ x, y float32 }
+	best := make(map[key]backtest.SweepResult)
+	var xs, ys []float32
+	for _, combination := range sweep {
+		x, err := combination.Params.Value(xParam)
+		if err != nil {
+			return err
+		}
+		y, err := combination.Params.Value(yParam)
+		if err != nil {
+			return err
+		}
+
+		current, ok := best[key{x, y}]
+		if !ok {
+			xs, ys = appendUnique(xs, x), appendUnique(ys, y)
+		}
+		if !ok || combination.Score > current.Score {
+			best[key{x, y}] = combination
+		}
+	}
+	sort.Slice(xs, func(i, j int) bool { return xs[i] < xs[j] })
+	sort.Slice(ys, func(i, j int) bool { return ys[i] > ys[j] })
+
+	minScore, maxScore := math.Inf(1), math.Inf(-1)
+	for _, combination := range best {
+		score := float64(combination.Score)
+		if math.IsInf(score, 0) {
+			continue
+		}
+		minScore, maxScore = math.Min(minScore, score), math.Max(maxScore, score)
+	}
+
+	heatmap := heatmapView{XParam: xParam, YParam: yParam, Size: cellSize}
+	heatmap.Width = cellSize*float64(len(xs)) + 2*margin
+	heatmap.Height = cellSize*float64(len(ys)) + 2*margin
+	for i, x := range xs {
+		heatmap.XLabels = append(heatmap.XLabels, tick{Position: margin + cellSize*(float64(i)+0.5), Label: fmt.Sprint(x)})
+	}
+	for j, y := range ys {
+		heatmap.YLabels = append(heatmap.YLabels, tick{Position: margin + cellSize*(float64(j)+0.5), Label: fmt.Sprint(y)})
+		for i, x := range xs {
+			combination, ok := best[key{x, y}]
+			if !ok {
+				continue
+			}
+			heatmap.Cells = append(heatmap.Cells, cell{
+				X:       margin + cellSize*float64(i),
+				Y:       margin + cellSize*float64(j),
+				Color:   scoreColor(float64(combination.Score), minScore, maxScore),
+				Label:   fmt.Sprintf("%.2f", combination.Score),
+				Tooltip: fmt.Sprintf("Rank %v\n%v\nReturn %.2f%% Max drawdown %.2f%% Trades %v", combination.Rank, combination.Params, combination.Result.TotalReturn*100, combination.Result.MaxDrawdown*100, combination.Result.NumberOfTrades),
+			})
+		}
+	}
+
+	return heatmapPage.Execute(w, heatmap)
+}
+
+// Write the heatmap to a file
+func SaveHeatmap(path string, sweep []backtest.SweepResult, xParam, yParam string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteHeatmap(file, sweep, xParam, yParam); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+type heatmapView struct {
+	XParam, YParam      string
+	Width, Height, Size float64
+	XLabels, YLabels    []tick
+	Cells               []cell
+}
+
+type cell struct {
+	X, Y           float64
+	Color          string
+	Label, Tooltip string
+}
+
+func appendUnique(values []float32, value float32) []float32 {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// Map the score from red (the worst) to green (the best)
+func scoreColor(score, min, max float64) string {
+	ratio := 0.5
+	if max > min {
+		ratio = math.Max(0, math.Min(1, (score-min)/(max-min)))
+	}
+	if math.IsInf(score, 1) {
+		ratio = 1
+	}
+	return fmt.Sprintf("hsl(%.0f, 65%%, 55%%)", ratio*120)
+}
+
+var heatmapPage = template.Must(template.New("heatmap").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Sweep of {{.XParam}} and {{.YParam}}</title>
+<style>
+body { font-family: sans-serif; margin: 20px; color: #222; }
+.axis { font-size: 12px; fill: #444; }
+.score { font-size: 12px; fill: #111; }
+</style>
+</head>
+<body>
+<h1>Sweep of {{.XParam}} and {{.YParam}}</h1>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .Cells}}<g><title>{{.Tooltip}}</title><rect x="{{.X}}" y="{{.Y}}" width="{{$.Size}}" height="{{$.Size}}" fill="{{.Color}}" stroke="#fff"/><text class="score" x="{{.X}}" y="{{.Y}}" dx="35" dy="39" text-anchor="middle">{{.Label}}</text></g>
+{{end}}
+{{range .XLabels}}<text class="axis" x="{{.Position}}" y="50" text-anchor="middle">{{.Label}}</text>
+{{end}}
+{{range .YLabels}}<text class="axis" x="55" y="{{.Position}}" text-anchor="end" dy="4">{{.Label}}</text>
+{{end}}
+<text class="axis" x="60" y="20">{{.XParam}} &#8594;</text>
+<text class="axis" x="10" y="{{.Height}}" dy="-10">{{.YParam}} &#8593;</text>
+</svg>
+<p>Every cell shows the score of the best combination with those values, hover it for the details.</p>
+</body>
+</html>
+`))
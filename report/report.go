@@ -0,0 +1,439 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/frappaf/tradingBot/backtest"
+	"github.com/frappaf/tradingBot/data"
+)
+
+const (
+	width         = 1200.0
+	priceHeight   = 520.0
+	equityHeight  = 220.0
+	margin        = 60.0
+	bandsHeight   = 260.0
+	maxCandles    = 600  //Above this the candles are resampled to keep the chart readable
+	resampleRound = 3600 //The resampled period is a multiple of an hour
+)
+
+// Strategies can expose the levels drawn on the price chart, like the Breakout does
+type (
+	areasStrategy     interface{ InterestAreas() []data.Candle }
+	keyLevelsStrategy interface{ KeyLevels() []float32 }
+	fibonacciStrategy interface{ FibonacciLevels() []float32 }
+)
+
+// Write a self-contained HTML report of a single symbol backtest
+// It draws the candles with the interest areas, the key levels, the fibonacci levels and the trades,
+// then the equity curve and a summary of the metrics, followed by the Monte Carlo bands when the result has them
+func Write(w io.Writer, result backtest.Result) error {
+	if len(result.Candles) == 0 {
+		return fmt.Errorf("THE RESULT HAS NO CANDLES TO DRAW")
+	}
+	return page.Execute(w, build(result))
+}
+
+// Write the report to a file
+func Save(path string, result backtest.Result) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, result); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// The elements of the page, in SVG coordinates
+type view struct {
+	Title                 string
+	Result                backtest.Result
+	Width, Height         float64
+	Left, Right           float64
+	PriceTop, EquityTop   float64
+	Candles               []candleShape
+	Areas                 []band
+	KeyLevels, Fibonacci  []level
+	Trades                []tradeShape
+	EquityPath, PeakPath  string
+	PriceTicks, TimeTicks []tick
+	EquityTicks           []tick
+	MonteCarlo            bandsShape
+}
+
+// The equity percentile bands of the Monte Carlo
+type bandsShape struct {
+	Height                       float64
+	Outer, Inner, Median, Actual string
+	ValueTicks, TradeTicks       []tick
+}
+
+type candleShape struct {
+	X, Width, WickX, WickTop, WickBottom, BodyTop, BodyHeight float64
+	Up                                                        bool
+}
+
+type band struct {
+	Y, Height  float64
+	High, Low  float32
+	Triggering bool
+}
+
+type level struct {
+	Y     float64
+	Label string
+}
+
+type tradeShape struct {
+	EntryX, EntryY, ExitX, ExitY float64
+	Long, Win                    bool
+	Tooltip                      string
+}
+
+type tick struct {
+	Position float64
+	Label    string
+}
+
+// A linear mapping from values to coordinates, rounded to tenths of a pixel to keep the file small
+type scale struct {
+	min, max, from, to float64
+}
+
+func (s scale) at(value float64) float64 {
+	if s.max == s.min {
+		return (s.from + s.to) / 2
+	}
+	return math.Round((s.from+(value-s.min)/(s.max-s.min)*(s.to-s.from))*10) / 10
+}
+
+func build(result backtest.Result) view {
+	candles := downsample(result.Candles)
+
+	v := view{
+		Title:     fmt.Sprintf("Backtest of %v", result.Symbol),
+		Result:    result,
+		Width:     width,
+		Left:      margin,
+		Right:     width - margin/2,
+		PriceTop:  margin / 2,
+		EquityTop: margin/2 + priceHeight + margin,
+	}
+	v.Height = v.EquityTop + equityHeight + margin
+
+	first, last := candles[0].Timestamp, candles[len(candles)-1].Timestamp
+	step := float64(last-first) / math.Max(float64(len(candles)-1), 1)
+	x := scale{float64(first), float64(last) + step, v.Left, v.Right}
+
+	low, high := float64(candles[0].Low), float64(candles[0].High)
+	for _, candle := range candles {
+		low = math.Min(low, float64(candle.Low))
+		high = math.Max(high, float64(candle.High))
+	}
+	padding := (high - low) * 0.05
+	low, high = low-padding, high+padding
+	y := scale{low, high, v.PriceTop + priceHeight, v.PriceTop}
+
+	//Candles
+	candleWidth := math.Max((x.to-x.from)/float64(len(candles))*0.7, 1)
+	for _, candle := range candles {
+		open, close := y.at(float64(candle.Open)), y.at(float64(candle.Close))
+		center := x.at(float64(candle.Timestamp) + step/2)
+		v.Candles = append(v.Candles, candleShape{
+			X:          center - candleWidth/2,
+			Width:      candleWidth,
+			WickX:      center,
+			WickTop:    y.at(float64(candle.High)),
+			WickBottom: y.at(float64(candle.Low)),
+			BodyTop:    math.Min(open, close),
+			BodyHeight: math.Max(math.Abs(open-close), 0.5),
+			Up:         candle.Close >= candle.Open,
+		})
+	}
+
+	//Interest areas, the ones that triggered a trade are highlighted
+	triggering := make(map[[2]float32]bool)
+	for _, trade := range result.Trades {
+		triggering[[2]float32{trade.Area.High, trade.Area.Low}] = true
+	}
+	if strategy, ok := result.Strategy.(areasStrategy); ok {
+		for _, area := range strategy.InterestAreas() {
+			top, bottom := area.High, area.Low
+			if top < bottom {
+				top, bottom = bottom, top
+			}
+			if float64(bottom) > high || float64(top) < low {
+				continue
+			}
+			v.Areas = append(v.Areas, band{
+				Y:          y.at(math.Min(float64(top), high)),
+				Height:     y.at(math.Max(float64(bottom), low)) - y.at(math.Min(float64(top), high)),
+				High:       top,
+				Low:        bottom,
+				Triggering: triggering[[2]float32{area.High, area.Low}],
+			})
+		}
+	}
+
+	if strategy, ok := result.Strategy.(keyLevelsStrategy); ok {
+		v.KeyLevels = levels(strategy.KeyLevels(), y, "%.2f")
+	}
+	if strategy, ok := result.Strategy.(fibonacciStrategy); ok {
+		names := []string{"23.6%", "38.2%", "61.8%", "78.6%", "50%"}
+		for i, value := range strategy.FibonacciLevels() {
+			if float64(value) < low || float64(value) > high || i >= len(names) {
+				continue
+			}
+			v.Fibonacci = append(v.Fibonacci, level{Y: y.at(float64(value)), Label: fmt.Sprintf("Fib %v %.2f", names[i], value)})
+		}
+	}
+
+	//Trades
+	for _, trade := range result.Trades {
+		side := "LONG"
+		if trade.Side < 0 {
+			side = "SHORT"
+		}
+		v.Trades = append(v.Trades, tradeShape{
+			EntryX: x.at(float64(trade.EntryTimestamp) + step/2),
+			EntryY: y.at(float64(trade.EntryPrice)),
+			ExitX:  x.at(float64(trade.ExitTimestamp) + step/2),
+			ExitY:  y.at(float64(trade.ExitPrice)),
+			Long:   trade.Side > 0,
+			Win:    trade.NetPL > 0,
+			Tooltip: fmt.Sprintf("%v %v -> %v\nEntry %.2f Exit %.2f (%v)\nUnits %f Net P/L %.2f",
+				side, formatTime(trade.EntryTimestamp), formatTime(trade.ExitTimestamp),
+				trade.EntryPrice, trade.ExitPrice, trade.ExitReason, trade.Units, trade.NetPL),
+		})
+	}
+
+	v.PriceTicks = valueTicks(y, "%.0f")
+	v.TimeTicks = timeTicks(x, first, last)
+
+	//Equity curve and high-water mark
+	if len(result.Equity) > 0 {
+		minEquity, maxEquity := float64(result.Equity[0].Equity), float64(result.Equity[0].HighWaterMark)
+		for _, point := range result.Equity {
+			minEquity = math.Min(minEquity, float64(point.Equity))
+			maxEquity = math.Max(maxEquity, float64(point.HighWaterMark))
+		}
+		equityY := scale{minEquity, maxEquity, v.EquityTop + equityHeight, v.EquityTop}
+
+		for i, point := range result.Equity {
+			command := "L"
+			if i == 0 {
+				command = "M"
+			}
+			px := x.at(float64(point.Timestamp) + step/2)
+			v.EquityPath += fmt.Sprintf("%v%.1f %.1f ", command, px, equityY.at(float64(point.Equity)))
+			v.PeakPath += fmt.Sprintf("%v%.1f %.1f ", command, px, equityY.at(float64(point.HighWaterMark)))
+		}
+		v.EquityTicks = valueTicks(equityY, "%.0f")
+	}
+
+	if len(result.MonteCarlo.Bands) > 1 {
+		v.MonteCarlo = monteCarloBands(result, v.Left, v.Right)
+	}
+
+	return v
+}
+
+// Draw the 5-95 and the 25-75 percentile bands and the median of the Monte Carlo equity over the number of trades,
+// with the equity of the backtest after every trade
+func monteCarloBands(result backtest.Result, left, right float64) bandsShape {
+	bands := result.MonteCarlo.Bands
+	shape := bandsShape{Height: bandsHeight + margin}
+
+	low, high := float64(bands[0].P5), float64(bands[0].P95)
+	for _, band := range bands {
+		low, high = math.Min(low, float64(band.P5)), math.Max(high, float64(band.P95))
+	}
+	x := scale{0, float64(len(bands) - 1), left, right}
+	y := scale{low, high, margin/2 + bandsHeight, margin / 2}
+
+	area := func(lower, upper func(backtest.EquityBand) float32) string {
+		path := ""
+		for i, band := range bands {
+			command := "L"
+			if i == 0 {
+				command = "M"
+			}
+			path += fmt.Sprintf("%v%.1f %.1f ", command, x.at(float64(band.Trade)), y.at(float64(upper(band))))
+		}
+		for i := len(bands) - 1; i >= 0; i-- {
+			path += fmt.Sprintf("L%.1f %.1f ", x.at(float64(bands[i].Trade)), y.at(float64(lower(bands[i]))))
+		}
+		return path + "Z"
+	}
+	shape.Outer = area(func(band backtest.EquityBand) float32 { return band.P5 }, func(band backtest.EquityBand) float32 { return band.P95 })
+	shape.Inner = area(func(band backtest.EquityBand) float32 { return band.P25 }, func(band backtest.EquityBand) float32 { return band.P75 })
+	for i, band := range bands {
+		command := "L"
+		if i == 0 {
+			command = "M"
+		}
+		shape.Median += fmt.Sprintf("%v%.1f %.1f ", command, x.at(float64(band.Trade)), y.at(float64(band.P50)))
+	}
+
+	//The equity of the backtest after every trade, clipped to the range of the bands
+	equity := float64(result.InitialBalance)
+	shape.Actual = fmt.Sprintf("M%.1f %.1f ", x.at(0), y.at(equity))
+	for i, trade := range result.Trades {
+		if i >= len(bands)-1 {
+			break
+		}
+		equity += float64(trade.NetPL)
+		shape.Actual += fmt.Sprintf("L%.1f %.1f ", x.at(float64(i+1)), y.at(math.Max(low, math.Min(high, equity))))
+	}
+
+	shape.ValueTicks = valueTicks(y, "%.0f")
+	for i := 0; i < 6; i++ {
+		trade := (len(bands) - 1) * i / 5
+		shape.TradeTicks = append(shape.TradeTicks, tick{Position: x.at(float64(trade)), Label: fmt.Sprint(trade)})
+	}
+	return shape
+}
+
+// Reduce the candles to at most maxCandles resampling them
+func downsample(candles []data.Candle) []data.Candle {
+	if len(candles) <= maxCandles {
+		return candles
+	}
+
+	span := candles[len(candles)-1].Timestamp - candles[0].Timestamp
+	period := span / maxCandles
+	period += resampleRound - period%resampleRound
+	return data.Resample(candles, period)
+}
+
+// Map the values inside the range of the scale to horizontal lines
+func levels(values []float32, y scale, format string) []level {
+	sorted := append([]float32(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var res []level
+	for i, value := range sorted {
+		if i > 0 && value == sorted[i-1] || float64(value) < y.min || float64(value) > y.max {
+			continue
+		}
+		res = append(res, level{Y: y.at(float64(value)), Label: fmt.Sprintf(format, value)})
+	}
+	return res
+}
+
+// Five ticks evenly spaced on a vertical scale
+func valueTicks(y scale, format string) []tick {
+	ticks := make([]tick, 0, 5)
+	for i := 0; i < 5; i++ {
+		value := y.min + (y.max-y.min)*float64(i)/4
+		ticks = append(ticks, tick{Position: y.at(value), Label: fmt.Sprintf(format, value)})
+	}
+	return ticks
+}
+
+// Six date ticks evenly spaced on the time scale
+func timeTicks(x scale, first, last int64) []tick {
+	ticks := make([]tick, 0, 6)
+	for i := 0; i < 6; i++ {
+		timestamp := first + (last-first)*int64(i)/5
+		ticks = append(ticks, tick{Position: x.at(float64(timestamp)), Label: time.Unix(timestamp, 0).UTC().Format("2006-01-02")})
+	}
+	return ticks
+}
+
+func formatTime(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format("2006-01-02 15:04")
+}
+
+func (v view) PlotWidth() float64 { return v.Right - v.Left }
+
+func percent(value float32) string { return fmt.Sprintf("%.2f%%", value*100) }
+
+// Move a coordinate back by the half size of the markers and of the axis labels
+func offset(value float64) float64 { return value - 3 }
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{"percent": percent, "offset": offset}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 20px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 20px; }
+td { padding: 4px 16px 4px 0; }
+td:nth-child(odd) { color: #666; }
+svg { background: #fff; border: 1px solid #ddd; }
+.axis { font-size: 11px; fill: #666; }
+.label { font-size: 10px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Result}}
+<table>
+<tr><td>Initial balance</td><td>{{printf "%.2f" .InitialBalance}}</td><td>Final equity</td><td>{{printf "%.2f" .FinalEquity}}</td><td>Trades</td><td>{{.NumberOfTrades}}</td></tr>
+<tr><td>Total return</td><td>{{percent .TotalReturn}}</td><td>CAGR</td><td>{{percent .CAGR}}</td><td>Max drawdown</td><td>{{percent .MaxDrawdown}}</td></tr>
+<tr><td>Sharpe</td><td>{{printf "%.2f" .Sharpe}}</td><td>Sortino</td><td>{{printf "%.2f" .Sortino}}</td><td>Exposure time</td><td>{{percent .Exposure}}</td></tr>
+<tr><td>Win rate</td><td>{{percent .WinRate}}</td><td>Profit factor</td><td>{{printf "%.2f" .ProfitFactor}}</td><td>Average win / loss</td><td>{{printf "%.2f" .AverageWin}} / {{printf "%.2f" .AverageLoss}}</td></tr>
+<tr><td>Buy and hold return</td><td>{{percent .Benchmark.BuyAndHoldReturn}}</td><td>Random entries mean return</td><td>{{percent .Benchmark.RandomMeanReturn}}</td><td>Percentile vs random</td><td>{{percent .Benchmark.Percentile}}</td></tr>
+</table>
+{{end}}
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+<g>
+{{range .Areas}}<rect x="{{$.Left}}" y="{{.Y}}" width="{{$.PlotWidth}}" height="{{.Height}}" fill="{{if .Triggering}}#f4a261{{else}}#8ecae6{{end}}" fill-opacity="0.25"><title>Area {{printf "%.2f" .Low}} - {{printf "%.2f" .High}}</title></rect>
+{{end}}
+{{range .KeyLevels}}<line x1="{{$.Left}}" x2="{{$.Right}}" y1="{{.Y}}" y2="{{.Y}}" stroke="#457b9d" stroke-width="0.6" stroke-dasharray="4 3"><title>Key level {{.Label}}</title></line>
+{{end}}
+{{range .Fibonacci}}<line x1="{{$.Left}}" x2="{{$.Right}}" y1="{{.Y}}" y2="{{.Y}}" stroke="#e76f51" stroke-width="0.8" stroke-dasharray="1 3"/><text class="label" x="{{$.Right}}" y="{{.Y}}" dy="-2" text-anchor="end" fill="#e76f51">{{.Label}}</text>
+{{end}}
+{{range .Candles}}<line x1="{{.WickX}}" x2="{{.WickX}}" y1="{{.WickTop}}" y2="{{.WickBottom}}" stroke="#555" stroke-width="0.6"/><rect x="{{.X}}" y="{{.BodyTop}}" width="{{.Width}}" height="{{.BodyHeight}}" fill="{{if .Up}}#2a9d8f{{else}}#e63946{{end}}"/>
+{{end}}
+{{range .Trades}}<g><title>{{.Tooltip}}</title><line x1="{{.EntryX}}" y1="{{.EntryY}}" x2="{{.ExitX}}" y2="{{.ExitY}}" stroke="{{if .Win}}#2a9d8f{{else}}#e63946{{end}}" stroke-width="1.2"/><circle cx="{{.EntryX}}" cy="{{.EntryY}}" r="3.5" fill="{{if .Long}}#1d3557{{else}}#f4a261{{end}}"/><rect x="{{.ExitX | offset}}" y="{{.ExitY | offset}}" width="6" height="6" fill="{{if .Win}}#2a9d8f{{else}}#e63946{{end}}"/></g>
+{{end}}
+</g>
+{{range .PriceTicks}}<text class="axis" x="{{$.Left | offset}}" y="{{.Position}}" text-anchor="end" dy="4">{{.Label}}</text>
+{{end}}
+{{range .TimeTicks}}<text class="axis" x="{{.Position}}" y="{{$.EquityTop}}" dy="-24" text-anchor="middle">{{.Label}}</text>
+{{end}}
+<text class="axis" x="{{.Left}}" y="{{.EquityTop}}" dy="-6">Equity (blue) and high-water mark (grey)</text>
+<path d="{{.PeakPath}}" fill="none" stroke="#999" stroke-width="1"/>
+<path d="{{.EquityPath}}" fill="none" stroke="#1d3557" stroke-width="1.2"/>
+{{range .EquityTicks}}<text class="axis" x="{{$.Left | offset}}" y="{{.Position}}" text-anchor="end" dy="4">{{.Label}}</text>
+{{end}}
+</svg>
+<p>Entries are circles (dark for long, orange for short), exits are squares (green for a profit, red for a loss). Orange areas triggered a trade.</p>
+{{with .Result.MonteCarlo}}{{if .Runs}}
+<h2>Monte Carlo</h2>
+<p>{{.Runs}} runs resampling the {{.Trades}} trades ({{.Resampling}}).</p>
+<table>
+<tr><td></td><td>5%</td><td>25%</td><td>50%</td><td>75%</td><td>95%</td></tr>
+{{with .FinalEquity}}<tr><td>Final equity</td><td>{{printf "%.2f" .P5}}</td><td>{{printf "%.2f" .P25}}</td><td>{{printf "%.2f" .P50}}</td><td>{{printf "%.2f" .P75}}</td><td>{{printf "%.2f" .P95}}</td></tr>{{end}}
+{{with .MaxDrawdown}}<tr><td>Max drawdown</td><td>{{percent .P5}}</td><td>{{percent .P25}}</td><td>{{percent .P50}}</td><td>{{percent .P75}}</td><td>{{percent .P95}}</td></tr>{{end}}
+{{if .RuinProbability}}{{with .TradesToRuin}}<tr><td>Trades to ruin</td><td>{{printf "%.0f" .P5}}</td><td>{{printf "%.0f" .P25}}</td><td>{{printf "%.0f" .P50}}</td><td>{{printf "%.0f" .P75}}</td><td>{{printf "%.0f" .P95}}</td></tr>{{end}}{{end}}
+</table>
+<p>Probability of ruin (equity at {{percent .RuinLevel}} of the initial balance): {{percent .RuinProbability}}</p>
+{{end}}{{end}}
+{{if .MonteCarlo.Outer}}{{with .MonteCarlo}}<svg xmlns="http://www.w3.org/2000/svg" width="{{$.Width}}" height="{{.Height}}" viewBox="0 0 {{$.Width}} {{.Height}}">
+<path d="{{.Outer}}" fill="#8ecae6" fill-opacity="0.35"/>
+<path d="{{.Inner}}" fill="#8ecae6" fill-opacity="0.6"/>
+<path d="{{.Median}}" fill="none" stroke="#457b9d" stroke-width="1.2"/>
+<path d="{{.Actual}}" fill="none" stroke="#1d3557" stroke-width="1.2" stroke-dasharray="4 2"/>
+{{range .ValueTicks}}<text class="axis" x="{{$.Left | offset}}" y="{{.Position}}" text-anchor="end" dy="4">{{.Label}}</text>
+{{end}}
+{{range .TradeTicks}}<text class="axis" x="{{.Position}}" y="{{$.MonteCarlo.Height}}" dy="-12" text-anchor="middle">{{.Label}}</text>
+{{end}}
+</svg>
+<p>Equity after every trade: the 5-95% and the 25-75% percentile bands, the median (line) and the backtest (dashed).</p>
+{{end}}{{end}}
+</body>
+</html>
+`))
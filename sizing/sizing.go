@@ -0,0 +1,141 @@
+package sizing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// The information available to size a new position
+// Equity is the money the bot can use, Price the entry price and StopLoss the exit price if the trade goes wrong
+// Volatility is the standard deviation of the recent candle returns
+// PastPL are the net profit/loss of the closed trades, oldest first
+type Request struct {
+	Equity, Price, StopLoss float32
+	Volatility              float32
+	PastPL                  []float32
+}
+
+// A sizer retrieves the units of a new position
+type Sizer interface {
+	Units(request Request) float32
+}
+
+// Invest the whole equity on every trade
+type AllIn struct{}
+
+func (AllIn) Units(request Request) float32 {
+	return request.Equity / request.Price
+}
+
+// Risk a fixed fraction of the equity: if the stopLoss is reached the loss is Risk * equity
+type FixedFraction struct {
+	Risk float32
+}
+
+func (sizer FixedFraction) Units(request Request) float32 {
+	distance := utils.AbsDifference(request.Price, request.StopLoss)
+	if distance == 0 {
+		return 0
+	}
+	return sizer.Risk * request.Equity / distance
+}
+
+// Invest the same Notional (units * price) on every trade
+type FixedNotional struct {
+	Notional float32
+}
+
+func (sizer FixedNotional) Units(request Request) float32 {
+	return sizer.Notional / request.Price
+}
+
+// Invest the Kelly fraction of the equity, W - (1 - W) / R where W is the win rate and R the average win / average loss,
+// scaled by Fraction (e.g. 0.5 for half Kelly)
+// Until MinTrades trades are closed the statistics are not reliable, so the Fraction of the equity is invested
+type Kelly struct {
+	Fraction  float32
+	MinTrades int
+}
+
+func (sizer Kelly) Units(request Request) float32 {
+	if len(request.PastPL) < sizer.MinTrades || len(request.PastPL) == 0 {
+		return sizer.Fraction * request.Equity / request.Price
+	}
+
+	var wins, totalWin, totalLoss float32
+	for _, pl := range request.PastPL {
+		if pl > 0 {
+			wins++
+			totalWin += pl
+		} else {
+			totalLoss -= pl
+		}
+	}
+
+	losses := float32(len(request.PastPL)) - wins
+	if wins == 0 {
+		return 0
+	}
+	if losses == 0 || totalLoss == 0 {
+		return sizer.Fraction * request.Equity / request.Price
+	}
+
+	winRate := wins / float32(len(request.PastPL))
+	payoff := (totalWin / wins) / (totalLoss / losses)
+	kelly := winRate - (1-winRate)/payoff
+	if kelly <= 0 {
+		return 0
+	}
+
+	return sizer.Fraction * kelly * request.Equity / request.Price
+}
+
+// Size the position so that its expected move in one candle (volatility * notional) is Target * equity
+type VolatilityTarget struct {
+	Target float32
+}
+
+func (sizer VolatilityTarget) Units(request Request) float32 {
+	if request.Volatility <= 0 {
+		return 0
+	}
+	return sizer.Target * request.Equity / (request.Volatility * request.Price)
+}
+
+// Parse a sizer in the form NAME[:PARAMETER]
+//
+//	all-in
+//	fixed-fraction:RISK      e.g. fixed-fraction:0.01 risks 1% of the equity
+//	fixed-notional:NOTIONAL  e.g. fixed-notional:1000
+//	kelly:FRACTION           e.g. kelly:0.5 for half Kelly, it needs 20 trades before using the statistics
+//	volatility:TARGET        e.g. volatility:0.002
+func Parse(value string) (Sizer, error) {
+	name, parameter, hasParameter := strings.Cut(value, ":")
+	if name == "all-in" {
+		return AllIn{}, nil
+	}
+
+	if !hasParameter {
+		return nil, fmt.Errorf("SIZER %q NEEDS A PARAMETER, USE %v:VALUE", name, name)
+	}
+	parsed, err := strconv.ParseFloat(parameter, 32)
+	if err != nil || parsed <= 0 {
+		return nil, fmt.Errorf("INVALID PARAMETER %q FOR SIZER %v, IT MUST BE A POSITIVE NUMBER", parameter, name)
+	}
+	x := float32(parsed)
+
+	switch name {
+	case "fixed-fraction":
+		return FixedFraction{Risk: x}, nil
+	case "fixed-notional":
+		return FixedNotional{Notional: x}, nil
+	case "kelly":
+		return Kelly{Fraction: x, MinTrades: 20}, nil
+	case "volatility":
+		return VolatilityTarget{Target: x}, nil
+	}
+	return nil, fmt.Errorf("UNKNOWN SIZER %q, USE all-in, fixed-fraction, fixed-notional, kelly OR volatility", name)
+}
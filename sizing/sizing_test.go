@@ -0,0 +1,82 @@
+package sizing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSizers(t *testing.T) {
+	request := Request{Equity: 10000, Price: 100, StopLoss: 95, Volatility: 0.02}
+	withPL := func(pastPL ...float32) Request {
+		request := request
+		request.PastPL = pastPL
+		return request
+	}
+
+	tests := []struct {
+		name    string
+		sizer   Sizer
+		request Request
+		units   float32
+	}{
+		{"all in", AllIn{}, request, 100},
+		{"fixed fraction", FixedFraction{Risk: 0.01}, request, 20},
+		{"fixed fraction short", FixedFraction{Risk: 0.01}, Request{Equity: 10000, Price: 100, StopLoss: 110}, 10},
+		{"fixed fraction no distance", FixedFraction{Risk: 0.01}, Request{Equity: 10000, Price: 100, StopLoss: 100}, 0},
+		{"fixed notional", FixedNotional{Notional: 2500}, request, 25},
+		{"kelly before min trades", Kelly{Fraction: 0.5, MinTrades: 3}, withPL(10, -5), 50},
+		{"kelly no trades", Kelly{Fraction: 0.5}, request, 50},
+		//W = 2/3, R = 10/5 = 2, kelly = 2/3 - 1/6 = 0.5
+		{"kelly", Kelly{Fraction: 0.5, MinTrades: 3}, withPL(10, -5, 10), 25},
+		{"kelly no wins", Kelly{Fraction: 0.5, MinTrades: 2}, withPL(-10, -5), 0},
+		{"kelly no losses", Kelly{Fraction: 0.5, MinTrades: 2}, withPL(10, 5), 50},
+		//W = 1/3, R = 1, kelly < 0
+		{"kelly negative", Kelly{Fraction: 0.5, MinTrades: 3}, withPL(5, -5, -5), 0},
+		{"volatility", VolatilityTarget{Target: 0.002}, request, 10},
+		{"volatility unknown", VolatilityTarget{Target: 0.002}, Request{Equity: 10000, Price: 100}, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if units := test.sizer.Units(test.request); math.Abs(float64(units-test.units)) > 1e-3 {
+				t.Fatalf("expected %v units, got %v", test.units, units)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		sizer Sizer
+		fails bool
+	}{
+		{"all-in", AllIn{}, false},
+		{"fixed-fraction:0.01", FixedFraction{Risk: 0.01}, false},
+		{"fixed-notional:1000", FixedNotional{Notional: 1000}, false},
+		{"kelly:0.5", Kelly{Fraction: 0.5, MinTrades: 20}, false},
+		{"volatility:0.002", VolatilityTarget{Target: 0.002}, false},
+		{"kelly", nil, true},
+		{"fixed-fraction:-1", nil, true},
+		{"fixed-fraction:x", nil, true},
+		{"martingale:2", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			sizer, err := Parse(test.value)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", sizer)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sizer != test.sizer {
+				t.Fatalf("expected %+v, got %+v", test.sizer, sizer)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+const (
+	//Default parameters (see Params)
+	minDifference    float32 = 300.0
+	stopLossMultiple float32 = 1.5
+	takeProfitOffset float32 = 0.2
+	levelDistance    float32 = 50.0
+	rewardMargin     float32 = 1.1
+
+	maxTakeProfits       = 5 //Successive key levels proposed to scale out the position
+	daySeconds     int64 = 24 * 60 * 60
+)
+
+// The breakout strategy trades the price leaving an interesting area
+// It contains the collection of the daily history, the current area that contains the price
+// and the current daily candle built from the received candles
+// When the price breaks the current area by at least MinDifference it opens a position:
+// the takeProfit is the next key level and the stopLoss is MinDifference*StopLossMultiple away from the price
+// The signal also proposes the following key levels to scale out the position
+// The Params can be set before the Initialize
+// The Logger receives the logs of the strategy, if nil they go to the standard output (the bot sets its own)
+type Breakout struct {
+	Params                        Params
+	Collection                    data.Collection
+	Logger                        *utils.Logger
+	currentArea, currentDayCandle data.Candle
+	partialDay                    bool //The current day started before the first received candle
+}
+
+// Set the logger of the strategy
+func (breakout *Breakout) SetLogger(logger *utils.Logger) {
+	breakout.Logger = logger
+}
+
+// Retrieve the logger of the strategy
+func (breakout *Breakout) log() *utils.Logger {
+	if breakout.Logger == nil {
+		return utils.Default
+	}
+	return breakout.Logger
+}
+
+// Set the history of the collection and find the areas and the key levels
+func (breakout *Breakout) Initialize(history []data.Candle) {
+	breakout.currentArea = data.Candle{}
+	breakout.currentDayCandle = data.Candle{
+		Timestamp: 0,
+	}
+	breakout.partialDay = false
+
+	breakout.Params = breakout.Params.WithDefaults()
+	breakout.Collection.Params = breakout.Params.Areas
+
+	breakout.Collection.FetchDailyData(history)
+	breakout.Collection.FindInterestingAreasAndKeyLevels()
+}
+
+// Update the daily candle and search the area of the price
+// If there is no opened position and the price broke the current area it retrieves an Open signal
+func (breakout *Breakout) OnCandle(ctx context.Context, candle data.Candle, position int8) []Signal {
+
+	breakout.updateCurrentDailyCandle(candle)
+
+	//If the price in not in an interesting area yet search again
+	if breakout.currentArea.Close == 0.0 {
+		closest, err := breakout.findArea(candle)
+		if err == nil {
+			breakout.currentArea = closest
+			breakout.log().Printf("Price inside interesting area\n%v", closest.ToString())
+		} else {
+			breakout.log().Println("Area not yet discovered...")
+		}
+		return nil
+	}
+
+	//If there is an opened position it can't open another one
+	if position != Neutral {
+		return nil
+	}
+
+	params := breakout.Params
+	area := breakout.currentArea
+	low, high := utils.GetHighLow(area.High, area.Low)
+
+	//If the price is under the current area there is a possible short
+	if candle.Close < low && (low-candle.Close) >= params.MinDifference {
+		breakout.currentArea.Close = 0 //Need to find another area to condsider
+		tp := breakout.findNextInterestingLevel(candle.Close, Short) + params.MinDifference*params.TakeProfitOffset
+		sl := candle.Close + params.MinDifference*params.StopLossMultiple
+		if tp > 0 && math.Abs(float64(candle.Close)-float64(tp)) > math.Abs(float64(candle.Close)-float64(sl))-float64(params.MinDifference*params.RewardMargin) {
+			breakout.log().Println("Price under the area --> Short signal")
+			tps := breakout.findNextInterestingLevels(candle.Close, Short, maxTakeProfits)
+			for i := range tps {
+				tps[i] += params.MinDifference * params.TakeProfitOffset
+			}
+			return []Signal{{Action: Open, Side: Short, Price: candle.Close, StopLoss: sl, TakeProfit: tp, TakeProfits: tps, Area: area}}
+		}
+	} else if candle.Close > high && (candle.Close-high) >= params.MinDifference { //Else if the price is on top of the current area there is a possible long
+		breakout.currentArea.Close = 0 // Need to find another area to condsider
+		tp := breakout.findNextInterestingLevel(candle.Close, Long) - params.MinDifference*params.TakeProfitOffset
+		sl := candle.Close - params.MinDifference*params.StopLossMultiple
+		if tp > 0 && math.Abs(float64(candle.Close)-float64(tp)) > math.Abs(float64(candle.Close)-float64(sl))-float64(params.MinDifference*params.RewardMargin) {
+			breakout.log().Println("Price over the area --> Long signal")
+			tps := breakout.findNextInterestingLevels(candle.Close, Long, maxTakeProfits)
+			for i := range tps {
+				tps[i] -= params.MinDifference * params.TakeProfitOffset
+			}
+			return []Signal{{Action: Open, Side: Long, Price: candle.Close, StopLoss: sl, TakeProfit: tp, TakeProfits: tps, Area: area}}
+		}
+	}
+
+	return nil
+}
+
+// Retrieve the sorted key levels of the collection
+func (breakout *Breakout) KeyLevels() []float32 {
+	return breakout.Collection.KeyLevels
+}
+
+// Retrieve the closed daily candles the strategy knows
+func (breakout *Breakout) History() []data.Candle {
+	return breakout.Collection.History
+}
+
+// Retrieve the interest areas of the collection sorted by their High
+func (breakout *Breakout) InterestAreas() []data.Candle {
+	return breakout.Collection.InterestAreas
+}
+
+// Retrieve the fibonacci retracement levels of the collection
+func (breakout *Breakout) FibonacciLevels() []float32 {
+	return breakout.Collection.FibonacciLevels()
+}
+
+// Retrieve the current area and the current daily candle
+func (breakout *Breakout) ToString() string {
+	body := "CurrentArea:\n"
+	body += breakout.currentArea.ToString()
+
+	body += "\n\nCurrent daily candle:\n"
+	body += breakout.currentDayCandle.ToString()
+
+	return body
+}
+
+// Find and returns, if exists, the area that contains the given candle
+// If it not exists returns an empty candle and an error
+func (breakout *Breakout) findArea(can data.Candle) (data.Candle, error) {
+
+	index := binarySearchForCandles(breakout.Collection.InterestAreas, can, 0, len(breakout.Collection.InterestAreas))
+	if index == -1 {
+		return data.Candle{}, fmt.Errorf("AREA NOT FOUND")
+	}
+
+	return breakout.Collection.InterestAreas[index], nil
+}
+
+// Recursive binary search for candles
+func binarySearchForCandles(arr []data.Candle, can data.Candle, from, to int) int {
+	index := (to-from)/2 + from
+
+	if from > to {
+		return -1
+	}
+
+	if arr[index].Contains(can.High) {
+		return index
+	}
+
+	if arr[index].High < can.Close {
+
+		if index == len(arr)-1 {
+			return -1
+		}
+		return binarySearchForCandles(arr, can, index+1, to)
+	} else {
+		if index == 0 {
+			return -1
+		}
+		return binarySearchForCandles(arr, can, from, index-1)
+	}
+}
+
+// Find the next interesting levels for the take profit
+// If the position is long it search for the first level + delta  > value from the first to the last
+// If the position is short it search for the first level < value + delta from the last to the first
+// The delta is the LevelDistance of the params
+func (breakout *Breakout) findNextInterestingLevel(value float32, position int8) float32 {
+
+	delta := breakout.Params.LevelDistance
+
+	switch position {
+	case Short:
+
+		for i := len(breakout.Collection.KeyLevels) - 1; i >= 0; i-- {
+			if breakout.Collection.KeyLevels[i]+delta < value {
+				return float32(breakout.Collection.KeyLevels[i])
+			}
+		}
+		return 0
+	case Long:
+		for i := 0; i < len(breakout.Collection.KeyLevels); i++ {
+			if breakout.Collection.KeyLevels[i] > value+delta {
+				return float32(breakout.Collection.KeyLevels[i])
+			}
+		}
+		return 0
+	default:
+		return 0
+
+	}
+
+}
+
+// Find up to n successive interesting levels, each one is the next interesting level of the previous one
+func (breakout *Breakout) findNextInterestingLevels(value float32, position int8, n int) []float32 {
+	var levels []float32
+
+	for len(levels) < n {
+		level := breakout.findNextInterestingLevel(value, position)
+		if level == 0 {
+			break
+		}
+		levels = append(levels, level)
+		value = level
+	}
+
+	return levels
+}
+
+// Update the current daily candle with the candle
+// The days start at midnight UTC: when the candle belongs to a new day the current one is closed,
+// appended to the history and the areas are searched again, so the collection only sees closed days
+// If the first candle is not at midnight its day misses the candles before it, so it is dropped
+func (breakout *Breakout) updateCurrentDailyCandle(candle data.Candle) {
+	start := candle.Timestamp - candle.Timestamp%daySeconds
+
+	if start != breakout.currentDayCandle.Timestamp {
+
+		//If it's not the first day candle, it's a whole day and the history doesn't contain it yet
+		history := breakout.Collection.History
+		if breakout.currentDayCandle.Timestamp != 0 && !breakout.partialDay && (len(history) == 0 || history[len(history)-1].Timestamp < breakout.currentDayCandle.Timestamp) {
+			if log := breakout.log(); log.Enabled() {
+				log.PrintStatus("NEW CANDLE APPENDED", breakout.currentDayCandle.ToString())
+			}
+			breakout.Collection.History = append(breakout.Collection.History, breakout.currentDayCandle)
+			breakout.Collection.FindInterestingAreasAndKeyLevels()
+		}
+
+		breakout.partialDay = breakout.currentDayCandle.Timestamp == 0 && candle.Timestamp != start
+		breakout.currentDayCandle = candle
+		breakout.currentDayCandle.Timestamp = start
+
+	} else {
+
+		breakout.currentDayCandle.Close = candle.Close
+		breakout.currentDayCandle.Volume += candle.Volume
+		if candle.High > breakout.currentDayCandle.High {
+			breakout.currentDayCandle.High = candle.High
+		}
+		if candle.Low < breakout.currentDayCandle.Low {
+			breakout.currentDayCandle.Low = candle.Low
+		}
+	}
+}
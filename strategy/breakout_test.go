@@ -0,0 +1,60 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/frappaf/tradingBot/data"
+	"github.com/frappaf/tradingBot/utils"
+)
+
+// Candles of 30 minutes from start, the price goes up and down around 100
+func halfHourCandles(start int64, n int) []data.Candle {
+	candles := make([]data.Candle, n)
+	for i := range candles {
+		price := float32(100 + i%10)
+		candles[i] = data.Candle{Timestamp: start + int64(i)*1800, Open: price, Close: price + 1, High: price + 2, Low: price - 1, Volume: 1}
+	}
+	return candles
+}
+
+func TestBreakoutDropsThePartialFirstDay(t *testing.T) {
+	const firstDay int64 = 1640995200 //2022-01-01
+
+	var history []data.Candle
+	for i := int64(30); i > 0; i-- {
+		history = append(history, data.Candle{Timestamp: firstDay - i*daySeconds, Open: 100, Close: 101, High: 110, Low: 90})
+	}
+	breakout := &Breakout{Logger: utils.Discard}
+	breakout.Initialize(history)
+
+	//The replay starts at noon of the first day and lasts until the third day
+	for _, candle := range halfHourCandles(firstDay+daySeconds/2, 24+48+1) {
+		breakout.updateCurrentDailyCandle(candle)
+	}
+
+	got := breakout.History()
+	if len(got) != len(history)+1 {
+		t.Fatalf("expected %v days in the history, got %v", len(history)+1, len(got))
+	}
+	if last := got[len(got)-1].Timestamp; last != firstDay+daySeconds {
+		t.Fatalf("expected the second day to be the last one of the history, got %v", last)
+	}
+}
+
+func TestBreakoutKeepsTheFirstDayStartingAtMidnight(t *testing.T) {
+	const firstDay int64 = 1640995200 //2022-01-01
+
+	breakout := &Breakout{Logger: utils.Discard}
+	breakout.Initialize(nil)
+	for _, candle := range halfHourCandles(firstDay, 48+1) {
+		breakout.updateCurrentDailyCandle(candle)
+	}
+
+	got := breakout.History()
+	if len(got) != 1 || got[0].Timestamp != firstDay {
+		t.Fatalf("expected the first day in the history, got %v", got)
+	}
+	if got[0].Open != 100 || got[0].High != 111 || got[0].Low != 99 {
+		t.Fatalf("the first day doesn't aggregate its candles: %+v", got[0])
+	}
+}
@@ -0,0 +1,233 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+// The parameters of the breakout strategy
+// MinDifference is the distance of the price from the area that opens a position
+// the stopLoss is StopLossMultiple*MinDifference away from the price and the takeProfit is
+// TakeProfitOffset*MinDifference before the next key level
+// The next key level is the first one farther than LevelDistance from the price, and the position is opened only if
+// the takeProfit is farther than the stopLoss minus RewardMargin*MinDifference
+// Areas tunes the area detection of the collection
+// The zero Params takes the default values, otherwise every field is used as it is (start from DefaultParams to change a few):
+// the zero Params is never valid (the min difference must be positive), so it can't be set on purpose
+type Params struct {
+	MinDifference    float32
+	StopLossMultiple float32
+	TakeProfitOffset float32
+	LevelDistance    float32
+	RewardMargin     float32
+	Areas            data.Params
+}
+
+// The names of the parameters in ParseParams and ParseGrid
+const (
+	minDifferenceParam = "min-difference"
+	stopLossParam      = "stop-loss"
+	takeProfitParam    = "take-profit"
+	levelDistanceParam = "level-distance"
+	rewardMarginParam  = "reward-margin"
+	minRangeParam      = "min-range"
+	maxRangeParam      = "max-range"
+	bufferLengthParam  = "buffer-length"
+)
+
+// The names of the parameters
+var ParamNames = []string{minDifferenceParam, stopLossParam, takeProfitParam, levelDistanceParam, rewardMarginParam, minRangeParam, maxRangeParam, bufferLengthParam}
+
+// Retrieve the default parameters
+func DefaultParams() Params {
+	return Params{
+		MinDifference:    minDifference,
+		StopLossMultiple: stopLossMultiple,
+		TakeProfitOffset: takeProfitOffset,
+		LevelDistance:    levelDistance,
+		RewardMargin:     rewardMargin,
+		Areas:            data.DefaultParams(),
+	}
+}
+
+// Retrieve the default parameters in place of the zero Params, the zero Areas are replaced too
+func (params Params) WithDefaults() Params {
+	if params == (Params{}) {
+		return DefaultParams()
+	}
+	params.Areas = params.Areas.WithDefaults()
+	return params
+}
+
+// Check that the parameters (with the defaults) are usable
+func (params Params) Validate() error {
+	params = params.WithDefaults()
+	if params.MinDifference <= 0 || params.StopLossMultiple <= 0 {
+		return fmt.Errorf("MIN DIFFERENCE AND STOP LOSS MUST BE POSITIVE")
+	}
+	if params.TakeProfitOffset < 0 || params.LevelDistance < 0 || params.RewardMargin < 0 {
+		return fmt.Errorf("TAKE PROFIT, LEVEL DISTANCE AND REWARD MARGIN MUST NOT BE NEGATIVE")
+	}
+	return params.Areas.Validate()
+}
+
+// Retrieve the parameters in the format of ParseParams
+func (params Params) String() string {
+	params = params.WithDefaults()
+	return fmt.Sprintf("%v=%v,%v=%v,%v=%v,%v=%v,%v=%v,%v=%v,%v=%v,%v=%v",
+		minDifferenceParam, params.MinDifference,
+		stopLossParam, params.StopLossMultiple,
+		takeProfitParam, params.TakeProfitOffset,
+		levelDistanceParam, params.LevelDistance,
+		rewardMarginParam, params.RewardMargin,
+		minRangeParam, params.Areas.MinRange,
+		maxRangeParam, params.Areas.MaxRange,
+		bufferLengthParam, params.Areas.BufferLength)
+}
+
+// Retrieve the named parameter (with the defaults)
+func (params Params) Value(name string) (float32, error) {
+	params = params.WithDefaults()
+	switch name {
+	case minDifferenceParam:
+		return params.MinDifference, nil
+	case stopLossParam:
+		return params.StopLossMultiple, nil
+	case takeProfitParam:
+		return params.TakeProfitOffset, nil
+	case levelDistanceParam:
+		return params.LevelDistance, nil
+	case rewardMarginParam:
+		return params.RewardMargin, nil
+	case minRangeParam:
+		return params.Areas.MinRange, nil
+	case maxRangeParam:
+		return params.Areas.MaxRange, nil
+	case bufferLengthParam:
+		return float32(params.Areas.BufferLength), nil
+	}
+	return 0, unknownParam(name)
+}
+
+func unknownParam(name string) error {
+	return fmt.Errorf("UNKNOWN PARAMETER %q, USE %v", name, strings.Join(ParamNames, ", "))
+}
+
+// Set the named parameter, the zero Params is replaced by the defaults first
+func (params *Params) set(name string, value float64) error {
+	*params = params.WithDefaults()
+	switch name {
+	case minDifferenceParam:
+		params.MinDifference = float32(value)
+	case stopLossParam:
+		params.StopLossMultiple = float32(value)
+	case takeProfitParam:
+		params.TakeProfitOffset = float32(value)
+	case levelDistanceParam:
+		params.LevelDistance = float32(value)
+	case rewardMarginParam:
+		params.RewardMargin = float32(value)
+	case minRangeParam:
+		params.Areas.MinRange = float32(value)
+	case maxRangeParam:
+		params.Areas.MaxRange = float32(value)
+	case bufferLengthParam:
+		params.Areas.BufferLength = int(value)
+	default:
+		return unknownParam(name)
+	}
+	return nil
+}
+
+// Parse a comma separated list of NAME=VALUE pairs (e.g. min-difference=300,stop-loss=1.5)
+// The missing parameters take the default values
+func ParseParams(value string) (Params, error) {
+	params := DefaultParams()
+	if strings.TrimSpace(value) == "" {
+		return params, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		name, raw, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return Params{}, fmt.Errorf("INVALID PARAMETER %q, USE NAME=VALUE", pair)
+		}
+		parsed, err := strconv.ParseFloat(raw, 32)
+		if err != nil {
+			return Params{}, fmt.Errorf("INVALID VALUE %q FOR %v", raw, name)
+		}
+		if err := params.set(name, parsed); err != nil {
+			return Params{}, err
+		}
+	}
+
+	return params, params.Validate()
+}
+
+// Parse a grid of parameters, a semicolon separated list of NAME=VALUE,VALUE... entries
+// (e.g. min-difference=200,300,400;stop-loss=1,1.5,2) and retrieve all their combinations
+// The parameters that are not in the grid take the default values, the invalid combinations are skipped
+// A parameter or a value repeated is rejected, so every combination is tried once
+func ParseGrid(value string) ([]Params, error) {
+	grid := []Params{DefaultParams()}
+	names := make(map[string]bool)
+
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, raw, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("INVALID GRID ENTRY %q, USE NAME=VALUE,VALUE", entry)
+		}
+		name = strings.TrimSpace(name)
+		if names[name] {
+			return nil, fmt.Errorf("%v IS REPEATED IN THE GRID, LIST ALL ITS VALUES IN ONE ENTRY", name)
+		}
+		names[name] = true
+
+		var values []float64
+		seen := make(map[float32]bool)
+		for _, item := range strings.Split(raw, ",") {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(item), 32)
+			if err != nil {
+				return nil, fmt.Errorf("INVALID VALUE %q FOR %v", item, name)
+			}
+			//The params are float32, so values that only differ beyond its precision are the same combination
+			if seen[float32(parsed)] {
+				return nil, fmt.Errorf("VALUE %v IS REPEATED FOR %v", strings.TrimSpace(item), name)
+			}
+			seen[float32(parsed)] = true
+			values = append(values, parsed)
+		}
+		sort.Float64s(values)
+
+		var combinations []Params
+		for _, params := range grid {
+			for _, value := range values {
+				if err := params.set(name, value); err != nil {
+					return nil, err
+				}
+				combinations = append(combinations, params)
+			}
+		}
+		grid = combinations
+	}
+
+	var valid []Params
+	for _, params := range grid {
+		if params.Validate() == nil {
+			valid = append(valid, params)
+		}
+	}
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("THE GRID HAS NO VALID COMBINATION")
+	}
+	return valid, nil
+}
@@ -0,0 +1,44 @@
+package strategy
+
+import (
+	"context"
+
+	"github.com/frappaf/tradingBot/data"
+)
+
+// Sides of a position
+const (
+	Long    int8 = 1
+	Short   int8 = -1
+	Neutral int8 = 0
+)
+
+// What the bot should do with a signal
+type Action int8
+
+const (
+	Open Action = iota
+	Close
+)
+
+// A signal is the decision of a strategy, the bot executes it
+// An Open signal asks to open a position on the Side at the Price with the given StopLoss and TakeProfit
+// TakeProfits are the successive levels where the position can be scaled out, the first one is the TakeProfit
+// A Close signal asks to close the current position at the Price
+// The Area is the interesting area that triggered the signal, if any
+type Signal struct {
+	Action                      Action
+	Side                        int8
+	Price, StopLoss, TakeProfit float32
+	TakeProfits                 []float32
+	Area                        data.Candle
+}
+
+// A strategy contains the signal logic
+// Initialize receives the daily history before the first candle, it contains only the closed days
+// OnCandle receives every new candle and the side of the current position and retrieves the signals to execute,
+// it must not use data after the candle (the backtests check it if the strategy has a History() []data.Candle method)
+type Strategy interface {
+	Initialize(history []data.Candle)
+	OnCandle(ctx context.Context, candle data.Candle, position int8) []Signal
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/frappaf/tradingBot/api/stub"
+)
+
+// Serve the candle fixtures with the finnhub API shape
+func runStub(flags *flag.FlagSet, args []string) error {
+	fixtures := flags.String("fixtures", "testdata", "Directory containing the candle fixtures")
+	addr := flags.String("addr", "localhost:8080", "Address to listen on")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	fmt.Printf("Serving the fixtures in %v on http://%v/api/v1\n", *fixtures, *addr)
+	return http.ListenAndServe(*addr, &stub.Server{Dir: *fixtures})
+}
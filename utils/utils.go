@@ -4,9 +4,25 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"math"
+	"os"
+	"sync"
 )
 
+//A logger writes the logs of a bot to its Output, a nil Output discards them
+//Every logger keeps its blocks whole, so bots running in different goroutines can share it
+type Logger struct {
+	Output io.Writer
+	mu     sync.Mutex
+}
+
+//The logger of the package functions, it writes to the standard output
+var Default = &Logger{Output: os.Stdout}
+
+//A logger that writes nothing, used to run the bots quietly (e.g. the optimisations)
+var Discard = &Logger{}
+
 //Retrieve the absolute difference between two float32
 func AbsDifference(x1, x2 float32) float32 { return float32(math.Abs(float64(x1 - x2))) }
 
@@ -23,10 +39,52 @@ func GetHighLow(x1, x2 float32) (high, low float32) {
 	return
 }
 
+//Check if the logger writes something, the callers can skip building the logs otherwise
+func (logger *Logger) Enabled() bool {
+	return logger != nil && logger.Output != nil && logger.Output != io.Discard
+}
+
 //Log with a title and a body
-func PrintStatus(title, body string) {
-	fmt.Printf("============================== *%v* ====================================\n", title)
-	fmt.Printf("%v\n", body)
-	fmt.Println("=====================================°============================================")
-	fmt.Println("")
+func (logger *Logger) PrintStatus(title, body string) {
+	if !logger.Enabled() {
+		return
+	}
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	fmt.Fprintf(logger.Output, "============================== *%v* ====================================\n", title)
+	fmt.Fprintf(logger.Output, "%v\n", body)
+	fmt.Fprintln(logger.Output, "=====================================°============================================")
+	fmt.Fprintln(logger.Output, "")
 }
+
+//Log a line like fmt.Println
+func (logger *Logger) Println(a ...any) {
+	if !logger.Enabled() {
+		return
+	}
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	fmt.Fprintln(logger.Output, a...)
+}
+
+//Log like fmt.Printf
+func (logger *Logger) Printf(format string, a ...any) {
+	if !logger.Enabled() {
+		return
+	}
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	fmt.Fprintf(logger.Output, format, a...)
+}
+
+//Log with a title and a body on the default logger
+func PrintStatus(title, body string) { Default.PrintStatus(title, body) }
+
+//Log a line like fmt.Println on the default logger
+func Println(a ...any) { Default.Println(a...) }
+
+//Log like fmt.Printf on the default logger
+func Printf(format string, a ...any) { Default.Printf(format, a...) }